@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine"
+)
+
+func TestBasic_trailingArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := engine.Tests{
+		{
+			Name:   "echo the trailing arguments after the dynamic ones",
+			Binary: "echo",
+			Args: engine.Args{
+				Args:         []string{"first"},
+				DynamicArgs:  []string{"unstored_trailing_key|=dynamic"},
+				TrailingArgs: []string{"--trailing"},
+			},
+			Assert: engine.Assertions{
+				Must: engine.Assertion{
+					Strict: true,
+					Output: []string{"first dynamic --trailing\n"},
+				},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
+
+func TestBasic_compareOutputSize(t *testing.T) {
+	t.Parallel()
+
+	const script = `case "$1" in
+--quiet) ;;
+--verbose) echo "resolving cluster"; echo "cluster started" ;;
+*) echo "cluster started" ;;
+esac`
+	tests := engine.Tests{
+		{
+			Name:   "assert the verbosity flags change the output",
+			Binary: "sh",
+			Args: engine.Args{
+				Args: []string{"-c", script, "sh"},
+			},
+			CompareOutputSize: []engine.OutputSizeComparison{
+				{Args: []string{"-c", script, "sh", "--quiet"}, Want: engine.Smaller},
+				{Args: []string{"-c", script, "sh", "--verbose"}, Want: engine.Larger},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
+
+func TestBasic_mustNotEchoInput(t *testing.T) {
+	t.Parallel()
+
+	tests := engine.Tests{
+		{
+			Name:   "assert the password prompt doesn't echo the password",
+			Binary: "sh",
+			Args: engine.Args{
+				Args:        []string{"-c", `read -r user; printf "Password: "; read -r pass; echo "logged in as $user"`},
+				Interactive: []string{"admin", "s3cr3t"},
+			},
+			Assert: engine.Assertions{
+				MustNotEchoInput: []string{"s3cr3t"},
+				Must: engine.Assertion{
+					Output: []string{"logged in as admin"},
+				},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
@@ -0,0 +1,56 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine"
+)
+
+// This test stores the output of a command as a baseline, which the output of
+// the next command is compared to, verifying a state transition.
+func TestBasic_baseline(t *testing.T) {
+	t.Parallel()
+
+	// This key must be unique on the whole integration suite.
+	const baselineKey = "baseline_deployments"
+
+	tests := engine.Tests{
+		{
+			Name:           "list the deployments before creating one",
+			Binary:         "printf",
+			Args:           engine.Args{Args: []string{`deployment-a\ndeployment-b\n`}},
+			StoreStdoutKey: baselineKey,
+		},
+		{
+			Name:   "list the deployments after creating one",
+			Binary: "printf",
+			Args:   engine.Args{Args: []string{`deployment-a\ndeployment-b\ndeployment-c\n`}},
+			Assert: engine.Assertions{
+				Must: engine.Assertion{
+					DifferentFrom: baselineKey,
+					SupersetOf:    baselineKey,
+				},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
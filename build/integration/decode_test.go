@@ -26,6 +26,7 @@ import (
 	"testing"
 
 	"github.com/elastic/testcli/pkg/engine"
+	"github.com/elastic/testcli/pkg/engine/callbacks"
 	"github.com/elastic/testcli/pkg/engine/teststorage"
 )
 
@@ -40,16 +41,18 @@ func TestBasic_decode(t *testing.T) {
 		HRefs   map[string]interface{} `json:"hrefs,omitempty"`
 	}
 
-	// This callback persist data outside of its boundary by using the pointer
-	// address of testOutput to decode the JSON. The length of HRefs is asserted
-	// at the end of the tests.
-	var testOutput echoTestData
+	// This callback persist data outside of its boundary by adding the decoded
+	// JSON to the decoded results, which are safe to add to from tests running
+	// in parallel. The length of HRefs is asserted at the end of the tests.
+	var decoded engine.Results[echoTestData]
 	decodeEchoData := func(out []byte, key string, storage teststorage.Storage) error {
-		if err := json.Unmarshal(out, &testOutput); err != nil {
+		var d echoTestData
+		if err := json.Unmarshal(out, &d); err != nil {
 			return err
 		}
 
-		storage.Set(key, testOutput.Message)
+		decoded.Add(d)
+		storage.Set(key, d.Message)
 		return nil
 	}
 
@@ -133,7 +136,54 @@ func TestBasic_decode(t *testing.T) {
 	engine.ExecuteTests(t, tests)
 
 	// Assert that the output of the hrefs is 5.
-	if length := len(testOutput.HRefs); length != 5 {
+	results := decoded.All()
+	if len(results) != 1 {
+		t.Fatalf("expected a single decoded result but got: %d", len(results))
+	}
+	if length := len(results[0].HRefs); length != 5 {
 		t.Errorf("expected hrefs to contain 5 items but got: %d", length)
 	}
 }
+
+// This test case decodes the same JSON structure as TestBasic_decode, with the
+// callbacks provided by the callbacks package.
+func TestBasic_decodeWithCallbacks(t *testing.T) {
+	t.Parallel()
+
+	// These keys must be unique on the whole integration suite.
+	const (
+		messageKey = "stored_callbacks_message"
+		hrefKey    = "stored_callbacks_href"
+	)
+
+	tests := engine.Tests{
+		{
+			Name:   "decode json",
+			Binary: "echo",
+			Args: engine.Args{
+				Args: []string{`{"message":"You Know, for Cloud.","hrefs":{"api/v0":"https://api.elastic-cloud.com/api/v0","api/latest":"https://api.elastic-cloud.com/api/latest","api/v1":"https://api.elastic-cloud.com/api/v1","app":"https://api.elastic-cloud.com/app","api/v0.1":"https://api.elastic-cloud.com/api/v0.1"}}`},
+			},
+			Callbacks: engine.TestCallback{
+				messageKey: callbacks.JSONField("message"),
+				hrefKey:    callbacks.JSONKeys("hrefs"),
+			},
+		},
+		{
+			Parallel: true,
+			Name:     "print decoded json field and hrefs and assert them",
+			Binary:   "echo",
+			Args: engine.Args{
+				DynamicArgs: []string{messageKey, hrefKey},
+			},
+			Assert: engine.Assertions{
+				Must: engine.Assertion{
+					Strict: true,
+					Output: []string{
+						"You Know, for Cloud. api/latest, api/v0, api/v0.1, api/v1, app\n",
+					},
+				},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
@@ -0,0 +1,61 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine"
+)
+
+func TestBasic_dependsOn(t *testing.T) {
+	t.Parallel()
+
+	// This key must be unique on the whole integration suite.
+	const slowKey = "stored_slow_output"
+
+	tests := engine.Tests{
+		{
+			Parallel:  true,
+			Name:      "print the slowly stored value",
+			Binary:    "echo",
+			DependsOn: []string{"slowly store a value"},
+			Args: engine.Args{
+				DynamicArgs: []string{slowKey},
+			},
+			Assert: engine.Assertions{
+				Must: engine.Assertion{
+					Strict: true,
+					Output: []string{"slow value\n\n"},
+				},
+			},
+		},
+		{
+			Parallel:       true,
+			Name:           "slowly store a value",
+			Binary:         "sh",
+			StoreStdoutKey: slowKey,
+			Args: engine.Args{
+				Args: []string{"-c", "sleep 0.5; echo slow value"},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
@@ -0,0 +1,68 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine"
+)
+
+// This test ensures a command uses an environment variable by setting it on
+// the test and asserting that its value is part of the output.
+func TestBasic_envPropagation(t *testing.T) {
+	t.Parallel()
+
+	tests := engine.Tests{
+		{
+			Name:   "assert the command reads the environment variable",
+			Binary: "sh",
+			Args: engine.Args{
+				Args: []string{"-c", `echo "using config from $TESTCLI_CONFIG_SOURCE"`},
+			},
+			Env: map[string]string{"TESTCLI_CONFIG_SOURCE": "/etc/testcli/config.yaml"},
+			Assert: engine.Assertions{
+				Must: engine.Assertion{
+					Dynamic: []string{engine.EnvKey("TESTCLI_CONFIG_SOURCE")},
+				},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
+
+// This test ensures a command doesn't depend on the locale by running it again
+// with another locale and comparing the output of both runs.
+func TestBasic_envVariants(t *testing.T) {
+	t.Parallel()
+
+	tests := engine.Tests{
+		{
+			Name:   "assert the output doesn't depend on the locale",
+			Binary: "sh",
+			Args: engine.Args{
+				Args: []string{"-c", "echo cluster started"},
+			},
+			Env:         map[string]string{"LC_ALL": "C"},
+			EnvVariants: []map[string]string{{"LC_ALL": "POSIX", "TESTCLI_REGION": "eu-west-1"}},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
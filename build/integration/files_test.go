@@ -0,0 +1,68 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine"
+)
+
+func TestBasic_files(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tests := engine.Tests{
+		{
+			Name:        "copy a file created by a pre command",
+			Binary:      "cp",
+			WorkingDir:  dir,
+			PreCommands: [][]string{{"sh", "-c", "echo some contents > source.txt"}},
+			Args: engine.Args{
+				Args: []string{"source.txt", "copy.txt"},
+			},
+			FileAssertions: []engine.FileAssertion{
+				{Path: "copy.txt", ContentContains: []string{"some contents"}},
+				{Path: "unexisting.txt"},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
+
+func TestBasic_tempDir(t *testing.T) {
+	t.Parallel()
+
+	tests := engine.Tests{
+		{
+			Name:    "write files to the scratch directory",
+			Binary:  "sh",
+			TempDir: true,
+			Args: engine.Args{
+				Args: []string{"-c", `echo report > "$OUT_DIR/report.txt"`, engine.TempDirPlaceholder},
+			},
+			Env: map[string]string{"OUT_DIR": engine.TempDirPlaceholder},
+			FileAssertions: []engine.FileAssertion{
+				{Path: engine.TempDirPlaceholder + "/report.txt", ContentContains: []string{"report"}},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build integration
+
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine"
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+)
+
+// This test runs the mock command, which behaves the same on every platform
+// unlike most of the system binaries.
+func TestBasic_mockCommand(t *testing.T) {
+	t.Parallel()
+
+	mock := enginetest.Build(t)
+	tests := engine.Tests{
+		{
+			Name:   "assert the output of the mock command",
+			Binary: mock,
+			Args: engine.Args{
+				Args: enginetest.MockCommand{
+					Stdout: "cluster started\n",
+					Stderr: "deprecated flag\n",
+				}.Args(),
+			},
+			Assert: engine.Assertions{
+				Must: engine.Assertion{
+					Strict: true,
+					Output: []string{"cluster started\n"},
+					Errors: []string{"deprecated flag"},
+				},
+			},
+		},
+		{
+			Name:   "assert the exit code of the mock command",
+			Binary: mock,
+			Args: engine.Args{
+				Args: enginetest.MockCommand{Stderr: "invalid argument\n", ExitCode: 2}.Args(),
+			},
+			Assert: engine.Assertions{
+				ExitCodeRange: &engine.ExitCodeRange{Min: 1, Max: 63},
+				Must: engine.Assertion{
+					EmptyOutput: true,
+					Errors:      []string{"invalid argument"},
+				},
+			},
+		},
+		{
+			Name:   "assert the mock command respects its timeout",
+			Binary: mock,
+			Args: engine.Args{
+				Args: enginetest.MockCommand{
+					Stderr:   "Error: timed out after 100ms\n",
+					ExitCode: 1,
+					Delay:    100 * time.Millisecond,
+				}.Args(),
+			},
+			Assert: engine.Assertions{
+				Timeout: &engine.TimeoutBehavior{
+					Within:      5 * time.Second,
+					ErrContains: "timed out",
+				},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
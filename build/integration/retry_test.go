@@ -0,0 +1,55 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build integration
+
+package integration
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine"
+)
+
+func TestBasic_retry(t *testing.T) {
+	t.Parallel()
+
+	// The command fails with a transient error the first time it's run.
+	flaky := `if [ -f "$0" ]; then echo recovered; else touch "$0"; echo "connection reset" >&2; exit 1; fi`
+
+	tests := engine.Tests{
+		{
+			Parallel:      true,
+			Name:          "retry on a transient error",
+			Binary:        "sh",
+			Retries:       2,
+			RetryOnStderr: []string{"connection reset"},
+			RetryBackoff:  10 * time.Millisecond,
+			Args: engine.Args{
+				Args: []string{"-c", flaky, filepath.Join(t.TempDir(), "flaky")},
+			},
+			Assert: engine.Assertions{
+				Must: engine.Assertion{
+					Output: []string{"recovered"},
+				},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
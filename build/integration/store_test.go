@@ -0,0 +1,68 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine"
+)
+
+func TestBasic_store(t *testing.T) {
+	t.Parallel()
+
+	// These keys must be unique on the whole integration suite.
+	const (
+		stdoutKey = "stored_stdout"
+		stderrKey = "stored_stderr"
+	)
+
+	tests := engine.Tests{
+		{
+			Name:           "store the raw output",
+			Binary:         "ls",
+			StoreStdoutKey: stdoutKey,
+			StoreStderrKey: stderrKey,
+			Args: engine.Args{
+				Args: []string{"store_test.go", "unexisting_path"},
+			},
+			Assert: engine.Assertions{
+				WantErr: true,
+			},
+		},
+		{
+			Parallel: true,
+			Name:     "print the stored output and assert it",
+			Binary:   "echo",
+			Args: engine.Args{
+				DynamicArgs: []string{stdoutKey, stderrKey},
+			},
+			Assert: engine.Assertions{
+				Must: engine.Assertion{
+					Output: []string{
+						"store_test.go",
+						"unexisting_path",
+					},
+				},
+			},
+		},
+	}
+	engine.ExecuteTests(t, tests)
+}
@@ -1,3 +1,9 @@
 module github.com/elastic/testcli
 
 go 1.20
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require golang.org/x/text v0.14.0
+
+require github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
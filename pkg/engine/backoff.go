@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"math"
+	"time"
+)
+
+// Backoff computes the delays between retries, which grow exponentially from
+// the base delay up to the maximum, randomized by the jitter.
+type Backoff struct {
+	// Delay before the first retry, which doubles on each retry.
+	Base time.Duration
+
+	// Maximum delay, no maximum when <= 0.
+	Max time.Duration
+
+	// Fraction between 0 and 1 of each delay which is randomly subtracted
+	// from it.
+	Jitter float64
+}
+
+// nominal returns the delay before the retry, without the jitter.
+func (b Backoff) nominal(retry int) time.Duration {
+	d := b.Base
+	for i := 0; i < retry && d > 0; i++ {
+		// Stops doubling at the maximum, or before the delay overflows.
+		if (b.Max > 0 && d >= b.Max) || d > math.MaxInt64/2 {
+			break
+		}
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// delay returns the delay before the retry, subtracting a random fraction of
+// up to the jitter. random returns a number in [0, 1).
+func (b Backoff) delay(retry int, random func() float64) time.Duration {
+	d := b.nominal(retry)
+	jitter := b.Jitter
+	if jitter <= 0 {
+		return d
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	return d - time.Duration(float64(d)*jitter*random())
+}
+
+// Schedule returns the delays before each of the retries, without the jitter,
+// which are their upper bounds.
+func (b Backoff) Schedule(retries int) []time.Duration {
+	schedule := make([]time.Duration, retries)
+	for i := range schedule {
+		schedule[i] = b.nominal(i)
+	}
+	return schedule
+}
@@ -0,0 +1,90 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackoff_delay(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		retry   int
+		random  float64
+		want    time.Duration
+	}{
+		{
+			name:    "no backoff",
+			backoff: Backoff{},
+			retry:   3,
+		},
+		{
+			name:    "doubles on each retry",
+			backoff: Backoff{Base: time.Second},
+			retry:   3,
+			want:    8 * time.Second,
+		},
+		{
+			name:    "caps the delay",
+			backoff: Backoff{Base: time.Second, Max: 5 * time.Second},
+			retry:   3,
+			want:    5 * time.Second,
+		},
+		{
+			name:    "doesn't overflow",
+			backoff: Backoff{Base: time.Second},
+			retry:   100,
+			want:    time.Second << 33,
+		},
+		{
+			name:    "subtracts the jitter",
+			backoff: Backoff{Base: time.Second, Jitter: 0.5},
+			retry:   1,
+			random:  0.5,
+			want:    1500 * time.Millisecond,
+		},
+		{
+			name:    "limits the jitter to the delay",
+			backoff: Backoff{Base: time.Second, Jitter: 2},
+			random:  0.99,
+			want:    10 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.backoff.delay(tt.retry, func() float64 { return tt.random })
+			if got != tt.want {
+				t.Errorf("delay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff_Schedule(t *testing.T) {
+	b := Backoff{Base: 100 * time.Millisecond, Max: time.Second, Jitter: 0.5}
+	want := []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		800 * time.Millisecond, time.Second, time.Second,
+	}
+	if got := b.Schedule(6); !reflect.DeepEqual(got, want) {
+		t.Errorf("Schedule() = %v, want %v", got, want)
+	}
+}
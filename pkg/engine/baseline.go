@@ -0,0 +1,66 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+// assertBaseline compares the output to the baselines stored in the storage
+// keys of DifferentFrom and SupersetOf.
+func assertBaseline(out string, w Assertion, storage teststorage.Storage) error {
+	var errs []error
+	if key := w.DifferentFrom; key != "" {
+		if baseline, ok := storage.Get(key); !ok {
+			errs = append(errs, fmt.Errorf("baseline key \"%s\" not found", key))
+		} else if out == baseline {
+			errs = append(errs, fmt.Errorf("standard output is the same as the baseline in key \"%s\": \"%s\"", key, out))
+		}
+	}
+
+	if key := w.SupersetOf; key != "" {
+		if baseline, ok := storage.Get(key); !ok {
+			errs = append(errs, fmt.Errorf("baseline key \"%s\" not found", key))
+		} else {
+			lines := make(map[string]bool)
+			for _, line := range splitLines(out) {
+				lines[line] = true
+			}
+
+			var missing []string
+			for _, line := range splitLines(baseline) {
+				if !lines[line] {
+					missing = append(missing, line)
+				}
+			}
+			if len(missing) > 0 {
+				errs = append(errs, fmt.Errorf("standard output is missing %d lines of the baseline in key \"%s\": %q",
+					len(missing), key, missing,
+				))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must compare to baseline", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func Test_assertBaseline(t *testing.T) {
+	storage := teststorage.NewSafeMap()
+	storage.Set("before", "deployment-a\ndeployment-b\n")
+
+	tests := []struct {
+		name string
+		out  string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "no baseline",
+			out:  "deployment-a\n",
+		},
+		{
+			name: "different from the baseline",
+			out:  "deployment-a\n",
+			w:    Assertion{DifferentFrom: "before"},
+		},
+		{
+			name: "same as the baseline",
+			out:  "deployment-a\ndeployment-b\n",
+			w:    Assertion{DifferentFrom: "before"},
+			err:  "standard output is the same as the baseline in key \"before\"",
+		},
+		{
+			name: "superset of the baseline",
+			out:  "deployment-a\ndeployment-c\ndeployment-b\n",
+			w:    Assertion{DifferentFrom: "before", SupersetOf: "before"},
+		},
+		{
+			name: "missing lines of the baseline",
+			out:  "deployment-c\ndeployment-b\n",
+			w:    Assertion{SupersetOf: "before"},
+			err:  "standard output is missing 1 lines of the baseline in key \"before\": [\"deployment-a\"]",
+		},
+		{
+			name: "missing baseline",
+			out:  "deployment-a\n",
+			w:    Assertion{SupersetOf: "after"},
+			err:  "baseline key \"after\" not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertBaseline(tt.out, tt.w, storage)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("assertBaseline() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("assertBaseline() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
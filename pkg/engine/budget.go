@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// retryBudget limits the total number of retries of a suite, so the suite
+// doesn't take forever when every test retries because a backend is down.
+// It's safe to use from parallel tests.
+type retryBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// newRetryBudget returns a budget of limit retries, or nil when the limit is
+// < 0, which doesn't limit the retries.
+func newRetryBudget(limit int) *retryBudget {
+	if limit < 0 {
+		return nil
+	}
+	return &retryBudget{limit: int64(limit)}
+}
+
+// take consumes a retry from the budget, returning false when it's
+// exhausted. A nil budget always allows the retry.
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	if b.used.Add(1) > b.limit {
+		b.used.Add(-1)
+		return false
+	}
+	return true
+}
+
+// remaining returns the number of retries left in the budget.
+func (b *retryBudget) remaining() int {
+	return int(b.limit - b.used.Load())
+}
+
+// String describes how much of the budget was used.
+func (b *retryBudget) String() string {
+	return fmt.Sprintf("%d of %d retries used, %d remaining", b.used.Load(), b.limit, b.remaining())
+}
+
+// WithRetryBudget limits the total number of retries of all the tests in the
+// suite. Once the budget is exhausted, the failed tests aren't retried
+// regardless of their Retries, which bounds the duration of the suite when a
+// whole backend is down. The usage of the budget is logged once the suite
+// finishes. A limit < 0 doesn't limit the retries, which is the default.
+func WithRetryBudget(limit int) Option {
+	return func(c *config) { c.retryBudget = newRetryBudget(limit) }
+}
@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"sync"
+	"testing"
+)
+
+func Test_retryBudget_take(t *testing.T) {
+	budget := newRetryBudget(5)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var taken int
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if budget.take() {
+				mu.Lock()
+				taken++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if taken != 5 {
+		t.Errorf("take() allowed %d retries, want 5", taken)
+	}
+	if got := budget.remaining(); got != 0 {
+		t.Errorf("remaining() = %d, want 0", got)
+	}
+	if got, want := budget.String(), "5 of 5 retries used, 0 remaining"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func Test_newRetryBudget(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		takes int
+	}{
+		{name: "Doesn't limit the retries when the limit is negative", limit: -1, takes: 100},
+		{name: "Doesn't allow retries when the limit is zero", limit: 0, takes: 0},
+		{name: "Allows the retries up to the limit", limit: 3, takes: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := newRetryBudget(tt.limit)
+			var taken int
+			for i := 0; i < 100 && budget.take(); i++ {
+				taken++
+			}
+			if taken != tt.takes {
+				t.Errorf("take() allowed %d retries, want %d", taken, tt.takes)
+			}
+		})
+	}
+}
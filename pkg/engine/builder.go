@@ -0,0 +1,205 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import "time"
+
+// TestBuilder builds a Test with chained calls, which can be easier to read
+// than the nested struct literals in large suites:
+//
+//	engine.NewTest("ls").WithBinary("ls").WithArgs("-1").MustContain("go.mod").Build()
+//
+// The built Test is the same value as the equivalent struct literal.
+type TestBuilder struct {
+	test Test
+}
+
+// NewTest returns a builder of a test with the name.
+func NewTest(name string) *TestBuilder {
+	return &TestBuilder{test: Test{Name: name}}
+}
+
+// WithBinary sets the binary which the test runs.
+func (b *TestBuilder) WithBinary(binary string) *TestBuilder {
+	b.test.Binary = binary
+	return b
+}
+
+// FindBinary finds the binary by walking the parent directories.
+func (b *TestBuilder) FindBinary() *TestBuilder {
+	b.test.FindBinary = true
+	return b
+}
+
+// WithArgs appends the arguments passed to the binary.
+func (b *TestBuilder) WithArgs(args ...string) *TestBuilder {
+	b.test.Args.Args = append(b.test.Args.Args, args...)
+	return b
+}
+
+// WithConfig appends the arguments passed before the rest of the arguments.
+func (b *TestBuilder) WithConfig(config ...string) *TestBuilder {
+	b.test.Args.Config = append(b.test.Args.Config, config...)
+	return b
+}
+
+// WithDynamicArgs appends the storage keys whose values are passed as
+// arguments.
+func (b *TestBuilder) WithDynamicArgs(keys ...string) *TestBuilder {
+	b.test.Args.DynamicArgs = append(b.test.Args.DynamicArgs, keys...)
+	return b
+}
+
+// WithInteractive appends the lines written to the standard input.
+func (b *TestBuilder) WithInteractive(lines ...string) *TestBuilder {
+	b.test.Args.Interactive = append(b.test.Args.Interactive, lines...)
+	return b
+}
+
+// WithWorkingDir sets the directory the command is run in.
+func (b *TestBuilder) WithWorkingDir(dir string) *TestBuilder {
+	b.test.WorkingDir = dir
+	return b
+}
+
+// WithEnv sets an environment variable of the command.
+func (b *TestBuilder) WithEnv(name, value string) *TestBuilder {
+	if b.test.Env == nil {
+		b.test.Env = make(map[string]string)
+	}
+	b.test.Env[name] = value
+	return b
+}
+
+// Parallel runs the test in parallel with the other parallel tests.
+func (b *TestBuilder) Parallel() *TestBuilder {
+	b.test.Parallel = true
+	return b
+}
+
+// DependsOn appends the names of the tests which must pass before this test.
+func (b *TestBuilder) DependsOn(names ...string) *TestBuilder {
+	b.test.DependsOn = append(b.test.DependsOn, names...)
+	return b
+}
+
+// WithRetries sets the number of retries and the backoff before the first.
+func (b *TestBuilder) WithRetries(retries int, backoff time.Duration) *TestBuilder {
+	b.test.Retries, b.test.RetryBackoff = retries, backoff
+	return b
+}
+
+// WithCallback sets the callback which is run with the storage key.
+func (b *TestBuilder) WithCallback(key string, callback Callback) *TestBuilder {
+	if b.test.Callbacks == nil {
+		b.test.Callbacks = make(TestCallback)
+	}
+	b.test.Callbacks[key] = callback
+	return b
+}
+
+// MustContain appends values which the standard output must contain.
+func (b *TestBuilder) MustContain(output ...string) *TestBuilder {
+	b.test.Assert.Must.Output = append(b.test.Assert.Must.Output, output...)
+	return b
+}
+
+// MustNotContain appends values which the standard output must not contain.
+func (b *TestBuilder) MustNotContain(output ...string) *TestBuilder {
+	b.test.Assert.Not.Output = append(b.test.Assert.Not.Output, output...)
+	return b
+}
+
+// MustContainErrors appends values which the standard error must contain.
+func (b *TestBuilder) MustContainErrors(errors ...string) *TestBuilder {
+	b.test.Assert.Must.Errors = append(b.test.Assert.Must.Errors, errors...)
+	return b
+}
+
+// MustNotContainErrors appends values which the standard error must not
+// contain.
+func (b *TestBuilder) MustNotContainErrors(errors ...string) *TestBuilder {
+	b.test.Assert.Not.Errors = append(b.test.Assert.Not.Errors, errors...)
+	return b
+}
+
+// MustMatch appends regex patterns which the standard output must match.
+func (b *TestBuilder) MustMatch(patterns ...string) *TestBuilder {
+	b.test.Assert.Must.Pattern = append(b.test.Assert.Must.Pattern, patterns...)
+	return b
+}
+
+// Strict makes the Must output match exactly.
+func (b *TestBuilder) Strict() *TestBuilder {
+	b.test.Assert.Must.Strict = true
+	return b
+}
+
+// StrictErrors makes the Must errors match exactly.
+func (b *TestBuilder) StrictErrors() *TestBuilder {
+	b.test.Assert.Must.StrictErrors = true
+	return b
+}
+
+// WantErr expects the command to exit with a non-zero exit code.
+func (b *TestBuilder) WantErr() *TestBuilder {
+	b.test.Assert.WantErr = true
+	return b
+}
+
+// CanError allows the command to exit with a non-zero exit code.
+func (b *TestBuilder) CanError() *TestBuilder {
+	b.test.Assert.CanError = true
+	return b
+}
+
+// Build returns the built test. The builder can be reused after Build, its
+// later calls don't change the tests which were already built.
+func (b *TestBuilder) Build() Test {
+	test := b.test
+	test.Args.Config = clip(test.Args.Config)
+	test.Args.Args = clip(test.Args.Args)
+	test.Args.DynamicArgs = clip(test.Args.DynamicArgs)
+	test.Args.Interactive = clip(test.Args.Interactive)
+	test.DependsOn = clip(test.DependsOn)
+	test.Assert.Must.Output = clip(test.Assert.Must.Output)
+	test.Assert.Must.Errors = clip(test.Assert.Must.Errors)
+	test.Assert.Must.Pattern = clip(test.Assert.Must.Pattern)
+	test.Assert.Not.Output = clip(test.Assert.Not.Output)
+	test.Assert.Not.Errors = clip(test.Assert.Not.Errors)
+
+	if b.test.Env != nil {
+		test.Env = make(map[string]string, len(b.test.Env))
+		for name, value := range b.test.Env {
+			test.Env[name] = value
+		}
+	}
+	if b.test.Callbacks != nil {
+		test.Callbacks = make(TestCallback, len(b.test.Callbacks))
+		for key, callback := range b.test.Callbacks {
+			test.Callbacks[key] = callback
+		}
+	}
+	return test
+}
+
+// clip removes the unused capacity of the slice, so the appends of the builder
+// allocate a new array rather than writing to the one of a built test.
+func clip(s []string) []string {
+	return s[:len(s):len(s)]
+}
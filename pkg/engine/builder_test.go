@@ -0,0 +1,158 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func TestTestBuilder(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *TestBuilder
+		want    Test
+	}{
+		{
+			name:    "Builds a test with only a name",
+			builder: NewTest("empty"),
+			want:    Test{Name: "empty"},
+		},
+		{
+			name: "Builds a test with the common fields",
+			builder: NewTest("list deployments").
+				WithBinary("ecl").FindBinary().
+				WithConfig("--region", "us-east-1").
+				WithArgs("deployment").WithArgs("list").
+				WithDynamicArgs("deployment_id").
+				WithInteractive("y").
+				WithWorkingDir("testdata").
+				WithEnv("EC_API_KEY", "secret").
+				Parallel().
+				DependsOn("create deployment").
+				WithRetries(2, time.Second).
+				MustContain("foo").MustNotContain("bar").
+				MustContainErrors("deprecated").MustNotContainErrors("panic").
+				MustMatch("[0-9a-f]{32}").
+				Strict().StrictErrors().
+				WantErr().CanError(),
+			want: Test{
+				Name:       "list deployments",
+				Binary:     "ecl",
+				FindBinary: true,
+				Args: Args{
+					Config:      []string{"--region", "us-east-1"},
+					Args:        []string{"deployment", "list"},
+					DynamicArgs: []string{"deployment_id"},
+					Interactive: []string{"y"},
+				},
+				WorkingDir:   "testdata",
+				Env:          map[string]string{"EC_API_KEY": "secret"},
+				Parallel:     true,
+				DependsOn:    []string{"create deployment"},
+				Retries:      2,
+				RetryBackoff: time.Second,
+				Assert: Assertions{
+					WantErr:  true,
+					CanError: true,
+					Must: Assertion{
+						Output:       []string{"foo"},
+						Errors:       []string{"deprecated"},
+						Pattern:      []string{"[0-9a-f]{32}"},
+						Strict:       true,
+						StrictErrors: true,
+					},
+					Not: Assertion{
+						Output: []string{"bar"},
+						Errors: []string{"panic"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.builder.Build(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTestBuilder_WithCallback(t *testing.T) {
+	var called string
+	callback := func(_ []byte, key string, _ teststorage.Storage) error {
+		called = key
+		return nil
+	}
+
+	test := NewTest("decode").WithCallback("message", callback).Build()
+	if err := test.Callbacks.Run(nil, teststorage.NewSafeMap()); err != nil {
+		t.Fatal(err)
+	}
+	if called != "message" {
+		t.Errorf("Build() callback called with key %q, want %q", called, "message")
+	}
+}
+
+func TestTestBuilder_reuseAfterBuild(t *testing.T) {
+	callback := func([]byte, string, teststorage.Storage) error { return nil }
+	builder := NewTest("list").
+		WithArgs("deployment").WithArgs("list").WithArgs("--all").
+		WithEnv("EC_REGION", "us-east-1").
+		WithCallback("id", callback).
+		MustContain("id").MustContain("name").MustContain("region")
+
+	first := builder.Build()
+	first.Args.Args = append(first.Args.Args, "--json")
+	first.Assert.Must.Output = append(first.Assert.Must.Output, "version")
+
+	builder.WithArgs("--yaml").WithEnv("EC_REGION", "eu-west-1").
+		WithEnv("EC_API_KEY", "secret").WithCallback("name", callback).
+		MustContain("status")
+	second := builder.Build()
+
+	if want := []string{"deployment", "list", "--all", "--json"}; !reflect.DeepEqual(first.Args.Args, want) {
+		t.Errorf("first Args = %q, want %q", first.Args.Args, want)
+	}
+	if want := []string{"id", "name", "region", "version"}; !reflect.DeepEqual(first.Assert.Must.Output, want) {
+		t.Errorf("first Must.Output = %q, want %q", first.Assert.Must.Output, want)
+	}
+	if want := map[string]string{"EC_REGION": "us-east-1"}; !reflect.DeepEqual(first.Env, want) {
+		t.Errorf("first Env = %v, want %v", first.Env, want)
+	}
+	if len(first.Callbacks) != 1 {
+		t.Errorf("first has %d callbacks, want 1", len(first.Callbacks))
+	}
+
+	if want := []string{"deployment", "list", "--all", "--yaml"}; !reflect.DeepEqual(second.Args.Args, want) {
+		t.Errorf("second Args = %q, want %q", second.Args.Args, want)
+	}
+	if want := []string{"id", "name", "region", "status"}; !reflect.DeepEqual(second.Assert.Must.Output, want) {
+		t.Errorf("second Must.Output = %q, want %q", second.Assert.Must.Output, want)
+	}
+	if want := map[string]string{"EC_REGION": "eu-west-1", "EC_API_KEY": "secret"}; !reflect.DeepEqual(second.Env, want) {
+		t.Errorf("second Env = %v, want %v", second.Env, want)
+	}
+	if len(second.Callbacks) != 2 {
+		t.Errorf("second has %d callbacks, want 2", len(second.Callbacks))
+	}
+}
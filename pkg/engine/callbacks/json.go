@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package callbacks provides ready to use engine.Callback constructors for the
+// most common ways of decoding a command's output into the test storage.
+package callbacks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/elastic/testcli/pkg/engine"
+	"github.com/elastic/testcli/pkg/engine/internal/jsonpath"
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+// JSONField returns a callback which decodes the JSON output and stores the
+// value found in the path (e.g. "message", "items[0].id", `hrefs["api/v1"]`).
+// Strings are stored as they are, any other value is stored encoded as JSON.
+func JSONField(path string) engine.Callback {
+	return func(out []byte, key string, storage teststorage.Storage) error {
+		value, err := decodePath(out, path)
+		if err != nil {
+			return err
+		}
+
+		if s, ok := value.(string); ok {
+			storage.Set(key, s)
+			return nil
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		storage.Set(key, string(encoded))
+		return nil
+	}
+}
+
+// JSONKeys returns a callback which decodes the JSON output and stores the
+// sorted keys of the object found in the path, joined by ", ". An empty path
+// selects the keys of the root object.
+func JSONKeys(path string) engine.Callback {
+	return func(out []byte, key string, storage teststorage.Storage) error {
+		value, err := decodePath(out, path)
+		if err != nil {
+			return err
+		}
+
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("value in path \"%s\" is not an object", path)
+		}
+
+		keys := make([]string, 0, len(object))
+		for k := range object {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		storage.Set(key, strings.Join(keys, ", "))
+		return nil
+	}
+}
+
+// appendMu serializes the read, append and write of the stored arrays, so
+// tests running in parallel don't lose each other's values.
+var appendMu sync.Mutex
+
+// AppendJSONArray returns a callback which decodes the JSON output and appends
+// the value found in the path to the JSON array stored in the key, storing the
+// array back. It accumulates the values of the tests which store to the same
+// key, such as the IDs of the resources created along a multi step test. The
+// array is created when the key isn't stored yet.
+func AppendJSONArray(path string) engine.Callback {
+	return func(out []byte, key string, storage teststorage.Storage) error {
+		value, err := decodePath(out, path)
+		if err != nil {
+			return err
+		}
+
+		appendMu.Lock()
+		defer appendMu.Unlock()
+
+		var array []interface{}
+		if stored, ok := storage.Get(key); ok {
+			dec := json.NewDecoder(strings.NewReader(stored))
+			dec.UseNumber()
+			if err := dec.Decode(&array); err != nil {
+				return fmt.Errorf("value stored in key \"%s\" is not a JSON array: %w", key, err)
+			}
+		}
+
+		encoded, err := json.Marshal(append(array, value))
+		if err != nil {
+			return err
+		}
+		storage.Set(key, string(encoded))
+		return nil
+	}
+}
+
+// decodePath decodes the JSON output and returns the value found in the path.
+// Numbers are kept as they were written in the output.
+func decodePath(out []byte, path string) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed decoding JSON output: %w", err)
+	}
+	return jsonpath.Get(doc, path)
+}
@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package callbacks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine"
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+const echoOutput = `{"message":"You Know, for Cloud.","version":1.10,"hrefs":{"api/v0":"https://api.elastic-cloud.com/api/v0","api/v0.1":"https://api.elastic-cloud.com/api/v0.1","app":"https://api.elastic-cloud.com/app"}}`
+
+func TestJSONCallbacks(t *testing.T) {
+	tests := []struct {
+		name     string
+		callback engine.Callback
+		out      string
+		want     string
+		err      string
+	}{
+		{
+			name:     "Stores a string field",
+			callback: JSONField("message"),
+			out:      echoOutput,
+			want:     "You Know, for Cloud.",
+		},
+		{
+			name:     "Stores a quoted field",
+			callback: JSONField(`hrefs["api/v0.1"]`),
+			out:      echoOutput,
+			want:     "https://api.elastic-cloud.com/api/v0.1",
+		},
+		{
+			name:     "Stores a number field as it was written",
+			callback: JSONField("version"),
+			out:      echoOutput,
+			want:     "1.10",
+		},
+		{
+			name:     "Stores an object field as JSON",
+			callback: JSONField("hrefs"),
+			out:      `{"hrefs":{"app":"https://api.elastic-cloud.com/app"}}`,
+			want:     `{"app":"https://api.elastic-cloud.com/app"}`,
+		},
+		{
+			name:     "Stores the sorted object keys",
+			callback: JSONKeys("hrefs"),
+			out:      echoOutput,
+			want:     "api/v0, api/v0.1, app",
+		},
+		{
+			name:     "Stores the sorted root keys",
+			callback: JSONKeys(""),
+			out:      echoOutput,
+			want:     "hrefs, message, version",
+		},
+		{
+			name:     "Fails when the keys path is not an object",
+			callback: JSONKeys("message"),
+			out:      echoOutput,
+			err:      "value in path \"message\" is not an object",
+		},
+		{
+			name:     "Fails when the path doesn't exist",
+			callback: JSONField("hrefs.api"),
+			out:      echoOutput,
+			err:      "path \"hrefs.api\" not found",
+		},
+		{
+			name:     "Fails when the output is not JSON",
+			callback: JSONField("message"),
+			out:      "You Know, for Cloud.",
+			err:      "failed decoding JSON output",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := teststorage.NewSafeMap()
+			err := tt.callback([]byte(tt.out), "key", storage)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("callback() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := storage.Get("key"); got != tt.want {
+				t.Errorf("callback() stored = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendJSONArray(t *testing.T) {
+	storage := teststorage.NewSafeMap()
+	callback := AppendJSONArray("id")
+	for _, out := range []string{`{"id": "a1"}`, `{"id": 2}`, `{"id": {"region": "us-east-1"}}`} {
+		if err := callback([]byte(out), "ids", storage); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := `["a1",2,{"region":"us-east-1"}]`
+	if got, _ := storage.Get("ids"); got != want {
+		t.Errorf("AppendJSONArray() stored = %v, want %v", got, want)
+	}
+
+	storage.Set("ids", "not an array")
+	err := callback([]byte(`{"id": "a1"}`), "ids", storage)
+	if want := "value stored in key \"ids\" is not a JSON array"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("AppendJSONArray() error = %v, want %v", err, want)
+	}
+}
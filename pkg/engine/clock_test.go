@@ -0,0 +1,97 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+)
+
+// fakeClock is a Clock whose time only moves forward when waiting on it,
+// which makes the delays instant and deterministic.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestExecuteTests_clock(t *testing.T) {
+	clock := &fakeClock{}
+	tests := Tests{{
+		Name:          "wait an hour",
+		Binary:        mockBin,
+		Args:          mockArgs(enginetest.MockCommand{}),
+		WaitBeforeRun: time.Hour,
+	}}
+	ExecuteTests(t, tests, WithClock(clock))
+
+	if got := clock.Now().Sub(time.Time{}); got < time.Hour {
+		t.Errorf("ExecuteTests() waited %s on the clock, want at least %s", got, time.Hour)
+	}
+}
+
+func TestExecuteTests_lastTestCooldown(t *testing.T) {
+	tests := []struct {
+		name  string
+		tests Tests
+		min   time.Duration
+		max   time.Duration
+	}{
+		{
+			name:  "skips the cooldown of the last test",
+			tests: Tests{{Name: "last", Binary: mockBin, Args: mockArgs(enginetest.MockCommand{})}},
+		},
+		{
+			name: "waits the cooldown of the rest",
+			tests: Tests{
+				{Name: "first", Binary: mockBin, Args: mockArgs(enginetest.MockCommand{})},
+				{Name: "last", Binary: mockBin, Args: mockArgs(enginetest.MockCommand{})},
+			},
+			min: time.Hour,
+			max: 9 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{}
+			ExecuteTests(t, tt.tests, WithClock(clock), WithCooldown(time.Hour))
+
+			if got := clock.Now().Sub(time.Time{}); got < tt.min || got > tt.max {
+				t.Errorf("ExecuteTests() waited %s on the clock, want within [%s, %s]", got, tt.min, tt.max)
+			}
+		})
+	}
+}
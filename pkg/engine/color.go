@@ -0,0 +1,60 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import "os"
+
+// NoColorEnv is the environment variable which disables the colors of the
+// failure and warning labels when it's set to any non-empty value, following
+// the https://no-color.org convention.
+const NoColorEnv = "NO_COLOR"
+
+const (
+	// Plain fail text
+	failPlain = "FAIL"
+	// Plain warn text
+	warnPlain = "WARN"
+)
+
+// isTerminal reports whether the file is a terminal, as opposed to a pipe or
+// a regular file which captures the output.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// failLabel returns the label which prefixes the failure messages, colored
+// when the colors are enabled.
+func (c config) failLabel() string {
+	if c.color {
+		return failRed
+	}
+	return failPlain
+}
+
+// warnLabel returns the label which prefixes the warning messages, colored
+// when the colors are enabled.
+func (c config) warnLabel() string {
+	if c.color {
+		return warnYellow
+	}
+	return warnPlain
+}
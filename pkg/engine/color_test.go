@@ -0,0 +1,93 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_config_labels(t *testing.T) {
+	tests := []struct {
+		name  string
+		color bool
+		fail  string
+		warn  string
+	}{
+		{
+			name:  "Colors the labels",
+			color: true,
+			fail:  "\x1b[31;1mFAIL\x1b[0m",
+			warn:  "\x1b[33;1mWARN\x1b[0m",
+		},
+		{
+			name: "Omits the color codes",
+			fail: "FAIL",
+			warn: "WARN",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config{color: tt.color}
+			if got := cfg.failLabel(); got != tt.fail {
+				t.Errorf("failLabel() = %q, want %q", got, tt.fail)
+			}
+			if got := cfg.warnLabel(); got != tt.warn {
+				t.Errorf("warnLabel() = %q, want %q", got, tt.warn)
+			}
+		})
+	}
+}
+
+func Test_newConfig_color(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		opts []Option
+	}{
+		{
+			name: "Disables the colors with the option",
+			opts: []Option{WithoutColor()},
+		},
+		{
+			name: "Disables the colors with the environment variable",
+			env:  "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(NoColorEnv, tt.env)
+			if newConfig(tt.opts...).color {
+				t.Error("newConfig() color = true, want false")
+			}
+		})
+	}
+}
+
+func Test_isTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "output"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if isTerminal(f) {
+		t.Error("isTerminal() = true for a regular file, want false")
+	}
+}
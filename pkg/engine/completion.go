@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+// CompletedAtKeyPrefix is the prefix of the storage keys where the time each
+// test finished is recorded, see CompletedAtKey. The keys are global, so
+// they're shared by namespaced storages.
+const CompletedAtKeyPrefix = teststorage.GlobalKeyPrefix + "testcli.completed_at."
+
+// CompletedAtKey returns the storage key where the time the test with the name
+// finished is recorded, formatted as time.RFC3339Nano.
+func CompletedAtKey(name string) string { return CompletedAtKeyPrefix + name }
+
+// CompletionOrder returns an error unless the tests with the names have
+// finished in the order of the names, such as a cache warming test which must
+// finish before the query test running in parallel.
+func CompletionOrder(storage teststorage.Storage, names ...string) error {
+	var previous time.Time
+	for i, name := range names {
+		value, ok := storage.Get(CompletedAtKey(name))
+		if !ok {
+			return fmt.Errorf("test \"%s\" hasn't finished", name)
+		}
+		completed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("completion time \"%s\" of test \"%s\" is invalid: %w", value, name, err)
+		}
+
+		if i > 0 && !completed.After(previous) {
+			return fmt.Errorf("test \"%s\" finished at %s, not after test \"%s\" at %s",
+				name, completed.Format(time.RFC3339Nano), names[i-1], previous.Format(time.RFC3339Nano),
+			)
+		}
+		previous = completed
+	}
+	return nil
+}
+
+func assertCompletionOrder(names []string, storage teststorage.Storage) error {
+	if len(names) == 0 {
+		return nil
+	}
+	if err := CompletionOrder(storage, names...); err != nil {
+		return NewPrefixedError("must complete in order", err)
+	}
+	return nil
+}
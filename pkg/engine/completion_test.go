@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func TestCompletionOrder(t *testing.T) {
+	storage := teststorage.NewSafeMap()
+	storage.Set(CompletedAtKey("warm cache"), "2024-03-01T12:00:00.1Z")
+	storage.Set(CompletedAtKey("query"), "2024-03-01T12:00:00.2Z")
+	storage.Set(CompletedAtKey("invalid"), "yesterday")
+
+	tests := []struct {
+		name  string
+		names []string
+		err   string
+	}{
+		{name: "no tests"},
+		{name: "finished in order", names: []string{"warm cache", "query"}},
+		{
+			name:  "finished out of order",
+			names: []string{"query", "warm cache"},
+			err:   "test \"warm cache\" finished at 2024-03-01T12:00:00.1Z, not after test \"query\" at 2024-03-01T12:00:00.2Z",
+		},
+		{
+			name:  "didn't finish",
+			names: []string{"warm cache", "cleanup"},
+			err:   "test \"cleanup\" hasn't finished",
+		},
+		{
+			name:  "invalid completion time",
+			names: []string{"invalid"},
+			err:   "completion time \"yesterday\" of test \"invalid\" is invalid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CompletionOrder(storage, tt.names...)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("CompletionOrder() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("CompletionOrder() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestExecuteTests_completedInOrder(t *testing.T) {
+	tests := Tests{
+		{Name: "completion first", Binary: mockBin, Args: mockArgs(enginetest.MockCommand{})},
+		{Name: "completion second", Binary: mockBin, Args: mockArgs(enginetest.MockCommand{})},
+		{
+			Name:   "completion check",
+			Binary: mockBin,
+			Args:   mockArgs(enginetest.MockCommand{}),
+			Assert: Assertions{Must: Assertion{
+				CompletedInOrder: []string{"completion first", "completion second"},
+			}},
+		},
+	}
+	ExecuteTests(t, tests, WithCooldown(0), WithNamespacedStorage())
+
+	if err := CompletionOrder(teststorage.GetInMemory(), "completion second", "completion check"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,74 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigFilePlaceholder is replaced in the arguments of a test with the path
+// of its ConfigFile.
+const ConfigFilePlaceholder = "{{.configfile}}"
+
+// ConfigFile is a file which is written before the test runs and removed once
+// it finishes, such as the config file of the command.
+type ConfigFile struct {
+	// Path of the file relative to the temporary directory it's written in,
+	// which allows the command to find it by its extension or name. Defaults
+	// to "config".
+	Path string
+
+	// Contents of the file.
+	Contents string
+}
+
+// write writes the file inside the directory, returning its path.
+func (f ConfigFile) write(dir string) (string, error) {
+	name := f.Path
+	if name == "" {
+		name = "config"
+	}
+	if filepath.IsAbs(name) || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("config file path \"%s\" must be relative to the temporary directory", name)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed creating the config file directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(f.Contents), 0o600); err != nil {
+		return "", fmt.Errorf("failed writing the config file: %w", err)
+	}
+	return path, nil
+}
+
+// expandPlaceholders returns a copy of the arguments with the placeholders
+// replaced by their values.
+func expandPlaceholders(args []string, values map[string]string) []string {
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		for placeholder, value := range values {
+			arg = strings.ReplaceAll(arg, placeholder, value)
+		}
+		expanded[i] = arg
+	}
+	return expanded
+}
@@ -0,0 +1,101 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+)
+
+func TestConfigFile_write(t *testing.T) {
+	tests := []struct {
+		name string
+		file ConfigFile
+		want string
+		err  string
+	}{
+		{
+			name: "defaults the file name",
+			file: ConfigFile{Contents: "region: us-east-1\n"},
+			want: "config",
+		},
+		{
+			name: "writes the file in a subdirectory",
+			file: ConfigFile{Path: ".ecl/config.yaml", Contents: "region: us-east-1\n"},
+			want: ".ecl/config.yaml",
+		},
+		{
+			name: "fails on a path outside the directory",
+			file: ConfigFile{Path: "../config.yaml"},
+			err:  "config file path \"../config.yaml\" must be relative to the temporary directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path, err := tt.file.write(dir)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("write() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := filepath.Join(dir, tt.want); path != want {
+				t.Errorf("write() = %v, want %v", path, want)
+			}
+			if contents, _ := os.ReadFile(path); string(contents) != tt.file.Contents {
+				t.Errorf("write() contents = %q, want %q", contents, tt.file.Contents)
+			}
+		})
+	}
+}
+
+func Test_expandPlaceholders(t *testing.T) {
+	args := []string{"--config", ConfigFilePlaceholder, "--config=" + ConfigFilePlaceholder, "list"}
+	got := expandPlaceholders(args, map[string]string{ConfigFilePlaceholder: "/tmp/config"})
+	if want := []string{"--config", "/tmp/config", "--config=/tmp/config", "list"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expandPlaceholders() = %v, want %v", got, want)
+	}
+	if args[1] != ConfigFilePlaceholder {
+		t.Errorf("expandPlaceholders() modified the arguments: %v", args)
+	}
+}
+
+func TestExecuteTests_configFile(t *testing.T) {
+	tests := Tests{
+		{
+			Name:       "reads the config file",
+			Binary:     mockBin,
+			Args:       mockArgs(enginetest.MockCommand{File: ConfigFilePlaceholder}),
+			ConfigFile: &ConfigFile{Path: "config.yaml", Contents: "region: us-east-1\n"},
+			Assert: Assertions{
+				Must: Assertion{Strict: true, Output: []string{"region: us-east-1\n"}},
+			},
+		},
+	}
+	ExecuteTests(t, tests, WithCooldown(0))
+}
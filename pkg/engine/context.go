@@ -0,0 +1,103 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+// Reserved storage keys which resolve to the details of the command that was
+// run by the test, when read from the storage that the assertions and the
+// callbacks receive. These are scoped to each test, so parallel tests don't
+// see each other's values.
+const (
+	// The binary which was run, after being resolved with FindBinary.
+	ExecutionBinaryKey = "testcli.execution.binary"
+
+	// The arguments the binary was run with, joined by spaces.
+	ExecutionArgsKey = "testcli.execution.args"
+
+	// The exit code of the command, -1 when it couldn't be run or it was
+	// terminated by a signal.
+	ExecutionExitCodeKey = "testcli.execution.exit_code"
+
+	// The time it took for the command to run, formatted as a time.Duration.
+	ExecutionDurationKey = "testcli.execution.duration"
+
+	// Prefix of the keys which resolve to the value of an environment
+	// variable of the command, see EnvKey.
+	ExecutionEnvKeyPrefix = "testcli.execution.env."
+)
+
+// EnvKey returns the reserved storage key which resolves to the value of the
+// environment variable the command was run with, including the Test Env
+// overrides. It's useful to assert that a command uses the variable, such as
+// with the Dynamic assertion. The key resolves to an empty value when the
+// variable isn't set.
+func EnvKey(name string) string { return ExecutionEnvKeyPrefix + name }
+
+// execution holds the details of a command's execution.
+type execution struct {
+	binary   string
+	args     []string
+	exitCode int
+	duration time.Duration
+	env      []string
+}
+
+// executionStorage is a view of the storage which resolves the reserved
+// execution keys to the details of a single test's execution. Any other key
+// is read from and written to the underlying storage.
+type executionStorage struct {
+	teststorage.Storage
+	execution execution
+}
+
+// Get obtains the value of the key, resolving the reserved execution keys.
+func (s executionStorage) Get(k string) (string, bool) {
+	switch k {
+	case ExecutionBinaryKey:
+		return s.execution.binary, true
+	case ExecutionArgsKey:
+		return strings.Join(s.execution.args, " "), true
+	case ExecutionExitCodeKey:
+		return strconv.Itoa(s.execution.exitCode), true
+	case ExecutionDurationKey:
+		return s.execution.duration.String(), true
+	}
+	if name, ok := strings.CutPrefix(k, ExecutionEnvKeyPrefix); ok {
+		return lookupEnv(s.execution.env, name), true
+	}
+	return s.Storage.Get(k)
+}
+
+// lookupEnv returns the value of the variable in the environment, where the
+// last entry of a variable takes precedence as in exec.Cmd.
+func lookupEnv(env []string, name string) string {
+	var value string
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == name {
+			value = v
+		}
+	}
+	return value
+}
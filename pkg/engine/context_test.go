@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func Test_executionStorage_Get(t *testing.T) {
+	safemap := teststorage.NewSafeMap()
+	safemap.Set("akey", "avalue")
+	storage := executionStorage{Storage: safemap, execution: execution{
+		binary:   "ecl",
+		args:     []string{"deployment", "list"},
+		exitCode: 1,
+		duration: 1500 * time.Millisecond,
+		env:      []string{"ECL_REGION=us-east-1", "ECL_OUTPUT=text", "ECL_OUTPUT=json"},
+	}}
+
+	tests := []struct {
+		name   string
+		key    string
+		want   string
+		wantOk bool
+	}{
+		{name: "Obtains the binary", key: ExecutionBinaryKey, want: "ecl", wantOk: true},
+		{name: "Obtains the args", key: ExecutionArgsKey, want: "deployment list", wantOk: true},
+		{name: "Obtains the exit code", key: ExecutionExitCodeKey, want: "1", wantOk: true},
+		{name: "Obtains the duration", key: ExecutionDurationKey, want: "1.5s", wantOk: true},
+		{name: "Obtains an environment variable", key: EnvKey("ECL_REGION"), want: "us-east-1", wantOk: true},
+		{name: "Obtains the last environment variable entry", key: EnvKey("ECL_OUTPUT"), want: "json", wantOk: true},
+		{name: "Obtains an unset environment variable", key: EnvKey("ECL_APIKEY"), want: "", wantOk: true},
+		{name: "Obtains a stored value", key: "akey", want: "avalue", wantOk: true},
+		{name: "Doesn't find an unexisting key", key: "unexisting key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := storage.Get(tt.key)
+			if got != tt.want {
+				t.Errorf("executionStorage.Get() got = %v, want %v", got, tt.want)
+			}
+			if ok != tt.wantOk {
+				t.Errorf("executionStorage.Get() ok = %v, want %v", ok, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestExecuteTests_shouldReadsExecution(t *testing.T) {
+	tests := Tests{{
+		Name:   "should read the exit code",
+		Binary: mockBin,
+		Args:   mockArgs(enginetest.MockCommand{Stdout: "exited with 0\n"}),
+		Assert: Assertions{Should: Assertion{Dynamic: []string{ExecutionExitCodeKey}}},
+	}}
+	report := ExecuteTestsWithReport(t, tests, WithCooldown(0))
+
+	if got := report.Tests(); len(got) != 1 || got[0].Status != StatusPassed {
+		t.Errorf("Tests() = %+v, want the Should assertion to find the exit code", got)
+	}
+}
@@ -0,0 +1,217 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// dependencies tracks the tests which other tests depend on, so the dependent
+// tests only run once these have passed.
+type dependencies struct {
+	// Names of the tests which other tests depend on.
+	required map[string]bool
+
+	mu       sync.Mutex
+	finished map[string]bool
+	passed   map[string]bool
+}
+
+// newDependencies validates the dependencies between the tests, returning nil
+// when no test depends on any other.
+func newDependencies(tests Tests) (*dependencies, error) {
+	required := make(map[string]bool)
+	for _, tt := range tests {
+		for _, name := range tt.DependsOn {
+			required[name] = true
+		}
+	}
+	if len(required) == 0 {
+		return nil, nil
+	}
+
+	var errs []error
+	index := make(map[string]int, len(tests))
+	for i, tt := range tests {
+		if _, ok := index[tt.Name]; ok {
+			errs = append(errs, fmt.Errorf("test name \"%s\" is not unique", tt.Name))
+			continue
+		}
+		index[tt.Name] = i
+	}
+
+	for _, tt := range tests {
+		for _, name := range tt.DependsOn {
+			if _, ok := index[name]; !ok {
+				errs = append(errs, fmt.Errorf("test \"%s\" depends on unknown test \"%s\"", tt.Name, name))
+			}
+		}
+	}
+
+	if cycle := findCycle(tests, index); cycle != nil {
+		errs = append(errs, fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	if len(errs) > 0 {
+		return nil, NewPrefixedError("dependencies", errors.Join(errs...))
+	}
+
+	return &dependencies{
+		required: required,
+		finished: make(map[string]bool),
+		passed:   make(map[string]bool),
+	}, nil
+}
+
+// findCycle returns the names of the tests which form the first dependency
+// cycle found, or nil when there are no cycles.
+func findCycle(tests Tests, index map[string]int) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(tests))
+	var path []string
+	var visit func(i int) []string
+	visit = func(i int) []string {
+		state[i] = visiting
+		path = append(path, tests[i].Name)
+		for _, name := range tests[i].DependsOn {
+			j, ok := index[name]
+			if !ok {
+				continue
+			}
+			if state[j] == visiting {
+				for k, n := range path {
+					if n == name {
+						return append(append([]string(nil), path[k:]...), name)
+					}
+				}
+			}
+			if state[j] == unvisited {
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+
+	for i := range tests {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// order returns the tests sorted so every test runs after the tests it depends
+// on, otherwise keeping their original order. The tests which other tests
+// depend on are made sequential, since parallel tests only start once all the
+// sequential ones have finished, so their dependents never wait on them while
+// holding one of the limited parallel slots.
+func (d *dependencies) order(tests Tests) Tests {
+	if d == nil {
+		return tests
+	}
+
+	index := make(map[string]int, len(tests))
+	for i, tt := range tests {
+		index[tt.Name] = i
+	}
+
+	// Kahn's algorithm, always picking the first available test.
+	pending := make([]int, len(tests))
+	dependents := make([][]int, len(tests))
+	for i, tt := range tests {
+		pending[i] = len(tt.DependsOn)
+		for _, name := range tt.DependsOn {
+			dependents[index[name]] = append(dependents[index[name]], i)
+		}
+	}
+
+	var available []int
+	for i := range tests {
+		if pending[i] == 0 {
+			available = append(available, i)
+		}
+	}
+
+	ordered := make(Tests, 0, len(tests))
+	for len(available) > 0 {
+		sort.Ints(available)
+		i := available[0]
+		available = available[1:]
+
+		tt := tests[i]
+		if d.required[tt.Name] {
+			tt.Parallel = false
+		}
+		ordered = append(ordered, tt)
+
+		for _, j := range dependents[i] {
+			if pending[j]--; pending[j] == 0 {
+				available = append(available, j)
+			}
+		}
+	}
+	return ordered
+}
+
+// check returns an error when any of the named tests didn't pass.
+func (d *dependencies) check(names []string) error {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, name := range names {
+		if !d.finished[name] {
+			return fmt.Errorf("dependency \"%s\" didn't run", name)
+		}
+		if !d.passed[name] {
+			return fmt.Errorf("dependency \"%s\" didn't pass", name)
+		}
+	}
+	return nil
+}
+
+// finish records whether the named test passed.
+func (d *dependencies) finish(name string, passed bool) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.finished[name] {
+		d.finished[name] = true
+		d.passed[name] = passed
+	}
+}
@@ -0,0 +1,133 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_newDependencies(t *testing.T) {
+	tests := []struct {
+		name  string
+		tests Tests
+		err   string
+		nil   bool
+	}{
+		{
+			name:  "Returns nil when there are no dependencies",
+			tests: Tests{{Name: "a"}, {Name: "a"}},
+			nil:   true,
+		},
+		{
+			name: "Accepts valid dependencies",
+			tests: Tests{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b"},
+				{Name: "c", Parallel: true, DependsOn: []string{"d", "b"}},
+				{Name: "d", Parallel: true},
+			},
+		},
+		{
+			name:  "Fails on unknown dependencies",
+			tests: Tests{{Name: "a", DependsOn: []string{"b"}}},
+			err:   "test \"a\" depends on unknown test \"b\"",
+		},
+		{
+			name:  "Fails on duplicated names",
+			tests: Tests{{Name: "a"}, {Name: "a"}, {Name: "b", DependsOn: []string{"a"}}},
+			err:   "test name \"a\" is not unique",
+		},
+		{
+			name: "Fails on cycles",
+			tests: Tests{
+				{Name: "a", Parallel: true, DependsOn: []string{"c"}},
+				{Name: "b", Parallel: true, DependsOn: []string{"a"}},
+				{Name: "c", Parallel: true, DependsOn: []string{"b"}},
+			},
+			err: "dependency cycle: a -> c -> b -> a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newDependencies(tt.tests)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("newDependencies() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == nil) != tt.nil {
+				t.Errorf("newDependencies() = %v, want nil %v", got, tt.nil)
+			}
+		})
+	}
+}
+
+func Test_dependencies_order(t *testing.T) {
+	tests := Tests{
+		{Name: "a", Parallel: true, DependsOn: []string{"c"}},
+		{Name: "b", Parallel: true},
+		{Name: "c", Parallel: true, DependsOn: []string{"d"}},
+		{Name: "d"},
+		{Name: "e"},
+	}
+	deps, err := newDependencies(tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Tests{
+		{Name: "b", Parallel: true},
+		{Name: "d"},
+		{Name: "c", DependsOn: []string{"d"}},
+		{Name: "a", Parallel: true, DependsOn: []string{"c"}},
+		{Name: "e"},
+	}
+	if got := deps.order(tests); !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencies.order() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_dependencies_check(t *testing.T) {
+	deps, err := newDependencies(Tests{
+		{Name: "a"},
+		{Name: "b"},
+		{Name: "c", DependsOn: []string{"a", "b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := deps.check([]string{"a"}); err == nil || err.Error() != "dependency \"a\" didn't run" {
+		t.Errorf("dependencies.check() error = %v", err)
+	}
+
+	deps.finish("a", true)
+	deps.finish("b", false)
+	if err := deps.check([]string{"a", "b"}); err == nil || err.Error() != "dependency \"b\" didn't pass" {
+		t.Errorf("dependencies.check() error = %v", err)
+	}
+	if err := deps.check([]string{"a"}); err != nil {
+		t.Errorf("dependencies.check() error = %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// maxDistinctSample is the maximum number of distinct values reported when a
+// DistinctCount isn't met.
+const maxDistinctSample = 5
+
+// DistinctCount ensures that a regex pattern captures a number of distinct
+// values from the standard output, such as the IDs of the listed resources
+// when the same resource can be listed more than once.
+type DistinctCount struct {
+	// Regex pattern which captures the value in its first group, or in the
+	// whole match when the pattern has no groups.
+	Pattern string
+
+	// Minimum number of distinct values, or the exact number when Exact is
+	// set.
+	Count int
+	Exact bool
+}
+
+func assertDistinctCounts(out string, counts []DistinctCount) error {
+	var errs []error
+	for _, c := range counts {
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("distinct pattern \"%s\" did not compile: %w", c.Pattern, err))
+			continue
+		}
+
+		seen := make(map[string]bool)
+		var values []string
+		for _, match := range re.FindAllStringSubmatch(out, -1) {
+			if value := firstGroup(match); !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+
+		want := "at least"
+		if c.Exact {
+			want = "exactly"
+		}
+		if got := len(values); (c.Exact && got != c.Count) || got < c.Count {
+			sample := values
+			if len(sample) > maxDistinctSample {
+				sample = sample[:maxDistinctSample]
+			}
+			errs = append(errs, fmt.Errorf(
+				"pattern \"%s\" captured %d distinct values, want %s %d, sample: %q",
+				c.Pattern, got, want, c.Count, sample,
+			))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must match distinct counts", errors.Join(errs...))
+	}
+	return nil
+}
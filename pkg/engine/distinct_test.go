@@ -0,0 +1,79 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_assertDistinctCounts(t *testing.T) {
+	const out = "id: a1\nid: b2\nid: a1\nid: c3\nid: b2\n"
+	tests := []struct {
+		name   string
+		counts []DistinctCount
+		err    string
+	}{
+		{
+			name:   "Passes when there are enough distinct values",
+			counts: []DistinctCount{{Pattern: `id: (\w+)`, Count: 3}},
+		},
+		{
+			name:   "Passes when there are exactly the distinct values",
+			counts: []DistinctCount{{Pattern: `id: (\w+)`, Count: 3, Exact: true}},
+		},
+		{
+			name:   "Counts the whole match without groups",
+			counts: []DistinctCount{{Pattern: `[a-z]\d`, Count: 2}},
+		},
+		{
+			name:   "Fails when there are fewer distinct values",
+			counts: []DistinctCount{{Pattern: `id: (\w+)`, Count: 5}},
+			err:    "pattern \"id: (\\w+)\" captured 3 distinct values, want at least 5, sample: [\"a1\" \"b2\" \"c3\"]",
+		},
+		{
+			name:   "Fails when there are more distinct values than the exact count",
+			counts: []DistinctCount{{Pattern: `id: (\w+)`, Count: 2, Exact: true}},
+			err:    "captured 3 distinct values, want exactly 2",
+		},
+		{
+			name:   "Fails on an invalid pattern",
+			counts: []DistinctCount{{Pattern: `(`, Count: 1}},
+			err:    "distinct pattern \"(\" did not compile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertDistinctCounts(out, tt.counts)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertDistinctCounts() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertDistinctCounts() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertDistinctCounts_sample(t *testing.T) {
+	out := strings.Repeat("id: x\n", 3) + "id: a\nid: b\nid: c\nid: d\nid: e\nid: f\n"
+	err := assertDistinctCounts(out, []DistinctCount{{Pattern: `id: (\w+)`, Count: 10}})
+	if want := "sample: [\"x\" \"a\" \"b\" \"c\" \"d\"]"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("assertDistinctCounts() error = %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,62 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// assertNoEcho ensures that none of the secrets which were written to the
+// command's standard input are found in its output, such as a password typed
+// on a prompt. The secrets must be interactive lines, and the ones which
+// weren't written because the command exited earlier aren't checked. The
+// failures refer to the line number so the secrets aren't leaked in them.
+func assertNoEcho(secrets, interactive []string, written int, stdout, stderr string) error {
+	var errs []error
+	for _, secret := range secrets {
+		n := indexOf(interactive, secret)
+		switch {
+		case n < 0:
+			errs = append(errs, fmt.Errorf("secret is not one of the %d interactive lines", len(interactive)))
+		case secret == "" || n >= written:
+			continue
+		case strings.Contains(stdout, secret):
+			errs = append(errs, fmt.Errorf("interactive line %d was echoed on the %s", n, Stdout))
+		case strings.Contains(stderr, secret):
+			errs = append(errs, fmt.Errorf("interactive line %d was echoed on the %s", n, Stderr))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must not echo input", errors.Join(errs...))
+	}
+	return nil
+}
+
+// indexOf returns the index of the first element equal to value, or -1 when
+// there's none.
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
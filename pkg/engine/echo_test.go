@@ -0,0 +1,81 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_assertNoEcho(t *testing.T) {
+	interactive := []string{"admin", "s3cr3t", "yes"}
+	tests := []struct {
+		name           string
+		secrets        []string
+		written        int
+		stdout, stderr string
+		err            string
+	}{
+		{
+			name:    "Passes when the secret isn't echoed",
+			secrets: []string{"s3cr3t"},
+			written: 3,
+			stdout:  "Username: admin\nPassword: \nLogged in\n",
+		},
+		{
+			name:    "Fails when the secret is echoed on the standard output",
+			secrets: []string{"s3cr3t"},
+			written: 3,
+			stdout:  "Username: admin\nPassword: s3cr3t\n",
+			err:     "interactive line 1 was echoed on the standard output",
+		},
+		{
+			name:    "Fails when the secret is echoed on the standard error",
+			secrets: []string{"s3cr3t"},
+			written: 3,
+			stderr:  "debug: read s3cr3t\n",
+			err:     "interactive line 1 was echoed on the standard error",
+		},
+		{
+			name:    "Ignores the lines which weren't written",
+			secrets: []string{"s3cr3t"},
+			written: 1,
+			stdout:  "Password: s3cr3t\n",
+		},
+		{
+			name:    "Fails when the secret isn't an interactive line",
+			secrets: []string{"other"},
+			written: 3,
+			err:     "secret is not one of the 3 interactive lines",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertNoEcho(tt.secrets, interactive, tt.written, tt.stdout, tt.stderr)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertNoEcho() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertNoEcho() error = %v, want %v", err, tt.err)
+			}
+			if err != nil && strings.Contains(err.Error(), "s3cr3t") {
+				t.Errorf("assertNoEcho() error = %v, leaks the secret", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"bytes"
+	"fmt"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// decodeOutput decodes the standard output and error from the named encoding
+// into UTF-8.
+func decodeOutput(name string, stdout, stderr *bytes.Buffer) (*bytes.Buffer, *bytes.Buffer, error) {
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unsupported output encoding \"%s\": %w", name, err)
+	}
+
+	decodedStdout, err := enc.NewDecoder().Bytes(stdout.Bytes())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed decoding standard output from %s: %w", name, err)
+	}
+
+	decodedStderr, err := enc.NewDecoder().Bytes(stderr.Bytes())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed decoding standard error from %s: %w", name, err)
+	}
+
+	return bytes.NewBuffer(decodedStdout), bytes.NewBuffer(decodedStderr), nil
+}
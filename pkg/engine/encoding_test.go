@@ -0,0 +1,78 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_decodeOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		encoding   string
+		stdout     []byte
+		stderr     []byte
+		wantStdout string
+		wantStderr string
+		err        string
+	}{
+		{
+			name:       "Decodes latin1",
+			encoding:   "latin1",
+			stdout:     []byte{'c', 'a', 'f', 0xe9},
+			stderr:     []byte{'a', 0xf1, 'o'},
+			wantStdout: "café",
+			wantStderr: "año",
+		},
+		{
+			name:       "Decodes UTF-16",
+			encoding:   "utf-16le",
+			stdout:     []byte{'o', 0, 'k', 0},
+			wantStdout: "ok",
+		},
+		{
+			name:     "Fails on unsupported encodings",
+			encoding: "klingon",
+			err:      "unsupported output encoding \"klingon\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := decodeOutput(tt.encoding,
+				bytes.NewBuffer(tt.stdout), bytes.NewBuffer(tt.stderr),
+			)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("decodeOutput() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("decodeOutput() stdout = %v, want %v", got, tt.wantStdout)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("decodeOutput() stderr = %v, want %v", got, tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package enginetest provides a mock command to test the engine, and the CLIs
+// tests, against a binary which behaves the same on every platform.
+package enginetest
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockPackage is the import path of the mock command.
+const mockPackage = "github.com/elastic/testcli/pkg/engine/enginetest/mockcmd"
+
+// MockCommand configures the behavior of the mock command.
+type MockCommand struct {
+	// Value printed on the standard output.
+	Stdout string
+
+	// Value printed on the standard error.
+	Stderr string
+
+	// Exit code of the command.
+	ExitCode int
+
+	// Time to wait before printing and exiting.
+	Delay time.Duration
+
+	// When set, the standard input is copied to the standard output until it's
+	// closed, before anything else is printed.
+	EchoStdin bool
+
+	// Path of a file whose contents are printed on the standard output.
+	File string
+
+	// When set, the working directory is printed on the standard output.
+	PrintWorkingDir bool
+
+	// Environment variables printed on the standard output as NAME=value
+	// lines.
+	PrintEnv []string
+
+	// Times the standard output and error are printed, both at once so that
+	// they're interleaved. Defaults to once.
+	Repeat int
+
+	// Time to wait after the standard output starts being printed before the
+	// standard error is, which orders them.
+	Pause time.Duration
+
+	// When set, a child process which inherits the standard output and error
+	// is started, keeping them open for the duration even after the command
+	// exits or is killed.
+	Orphan time.Duration
+}
+
+// Args returns the arguments which make the mock command behave as configured.
+func (m MockCommand) Args() []string {
+	args := []string{
+		"-stdout", m.Stdout,
+		"-stderr", m.Stderr,
+		"-exit", strconv.Itoa(m.ExitCode),
+		"-delay", m.Delay.String(),
+	}
+	if m.EchoStdin {
+		args = append(args, "-stdin")
+	}
+	if m.File != "" {
+		args = append(args, "-file", m.File)
+	}
+	if m.PrintWorkingDir {
+		args = append(args, "-pwd")
+	}
+	if len(m.PrintEnv) > 0 {
+		args = append(args, "-env", strings.Join(m.PrintEnv, ","))
+	}
+	if m.Repeat > 0 {
+		args = append(args, "-repeat", strconv.Itoa(m.Repeat))
+	}
+	if m.Pause > 0 {
+		args = append(args, "-pause", m.Pause.String())
+	}
+	if m.Orphan > 0 {
+		args = append(args, "-orphan", m.Orphan.String())
+	}
+	return args
+}
+
+// Build compiles the mock command into a temporary directory which is removed
+// once the test finishes, returning the path of the binary. It requires the Go
+// toolchain, the test is skipped when it isn't found.
+func Build(tb testing.TB) string {
+	tb.Helper()
+
+	if _, err := exec.LookPath("go"); err != nil {
+		tb.Skipf("the go toolchain is required to build the mock command: %s", err)
+	}
+
+	bin, err := BuildTo(tb.TempDir())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return bin
+}
+
+// BuildTo compiles the mock command into the directory, returning the path of
+// the binary. It's meant for a TestMain which builds it once for the whole
+// package, and removes the directory once the tests finish.
+func BuildTo(dir string) (string, error) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return "", fmt.Errorf("the go toolchain is required to build the mock command: %w", err)
+	}
+
+	bin := filepath.Join(dir, "mockcmd")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+
+	out, err := exec.Command(goBin, "build", "-o", bin, mockPackage).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed building the mock command: %w: %s", err, out)
+	}
+	return bin, nil
+}
@@ -0,0 +1,109 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package enginetest
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	bin := Build(t)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("region: us-east-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENGINETEST_MODE", "offline")
+
+	tests := []struct {
+		name       string
+		m          MockCommand
+		stdin      string
+		wantStdout string
+		wantStderr string
+		wantCode   int
+	}{
+		{
+			name:       "Prints the standard output and error",
+			m:          MockCommand{Stdout: "some output\n", Stderr: "a warning\n"},
+			wantStdout: "some output\n",
+			wantStderr: "a warning\n",
+		},
+		{
+			name:     "Exits with the exit code after the delay",
+			m:        MockCommand{ExitCode: 3, Delay: 10 * time.Millisecond},
+			wantCode: 3,
+		},
+		{
+			name:       "Echoes the standard input before the standard output",
+			m:          MockCommand{Stdout: "done\n", EchoStdin: true},
+			stdin:      "first\nsecond\n",
+			wantStdout: "first\nsecond\ndone\n",
+		},
+		{
+			name:       "Prints the file, the working directory and the environment",
+			m:          MockCommand{File: file, PrintWorkingDir: true, PrintEnv: []string{"ENGINETEST_MODE", "ENGINETEST_UNSET"}},
+			wantStdout: "region: us-east-1\n" + wd + "\nENGINETEST_MODE=offline\nENGINETEST_UNSET=\n",
+		},
+		{
+			name:       "Repeats the standard output and error",
+			m:          MockCommand{Stdout: "out\n", Stderr: "err\n", Repeat: 2, Pause: time.Millisecond},
+			wantStdout: "out\nout\n",
+			wantStderr: "err\nerr\n",
+		},
+		{
+			name:       "Leaves a child holding the output open",
+			m:          MockCommand{Stdout: "parent\n", Orphan: 10 * time.Millisecond},
+			wantStdout: "parent\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			cmd := exec.Command(bin, tt.m.Args()...)
+			cmd.Stdin, cmd.Stdout, cmd.Stderr = strings.NewReader(tt.stdin), &stdout, &stderr
+
+			var code int
+			var exitErr *exec.ExitError
+			if err := cmd.Run(); errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", got, tt.wantStdout)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr = %q, want %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Command mockcmd prints the configured standard output and error, and exits
+// with the configured exit code after an optional delay. It can also echo its
+// standard input, a file, its working directory and environment variables, and
+// leave a child process holding its output open. It behaves the same on every
+// platform, which makes it a predictable binary to test against.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	stdout := flag.String("stdout", "", "value printed on the standard output")
+	stderr := flag.String("stderr", "", "value printed on the standard error")
+	exitCode := flag.Int("exit", 0, "exit code")
+	delay := flag.Duration("delay", 0, "time to wait before printing and exiting")
+	echoStdin := flag.Bool("stdin", false, "copy the standard input to the standard output")
+	file := flag.String("file", "", "file printed on the standard output")
+	pwd := flag.Bool("pwd", false, "print the working directory")
+	env := flag.String("env", "", "comma separated environment variables printed as NAME=value")
+	repeat := flag.Int("repeat", 1, "times the standard output and error are printed")
+	pause := flag.Duration("pause", 0, "time to wait before printing the standard error")
+	orphan := flag.Duration("orphan", 0, "time a child process keeps the output open, regardless of the command")
+	flag.Parse()
+
+	if *orphan > 0 {
+		// The child isn't waited for, so it outlives the command.
+		child := exec.Command(os.Args[0], "-delay", orphan.String())
+		child.Stdout, child.Stderr = os.Stdout, os.Stderr
+		if err := child.Start(); err != nil {
+			fail(err)
+		}
+	}
+
+	time.Sleep(*delay)
+	if *echoStdin {
+		if _, err := io.Copy(os.Stdout, os.Stdin); err != nil {
+			fail(err)
+		}
+	}
+	if *file != "" {
+		contents, err := os.ReadFile(*file)
+		if err != nil {
+			fail(err)
+		}
+		os.Stdout.Write(contents)
+	}
+	if *pwd {
+		wd, err := os.Getwd()
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(wd)
+	}
+	if *env != "" {
+		for _, name := range strings.Split(*env, ",") {
+			fmt.Printf("%s=%s\n", name, os.Getenv(name))
+		}
+	}
+
+	// Both streams are written at once, so the reader sees them interleaved.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < *repeat; i++ {
+			fmt.Fprint(os.Stdout, *stdout)
+		}
+	}()
+	time.Sleep(*pause)
+	for i := 0; i < *repeat; i++ {
+		fmt.Fprint(os.Stderr, *stderr)
+	}
+	wg.Wait()
+	os.Exit(*exitCode)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(125)
+}
@@ -0,0 +1,57 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// mergeEnv returns a copy of the environment variables with the overrides.
+func mergeEnv(env, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(env)+len(overrides))
+	for name, value := range env {
+		merged[name] = value
+	}
+	for name, value := range overrides {
+		merged[name] = value
+	}
+	return merged
+}
+
+// describeEnv returns the environment variables as sorted "NAME=value" pairs.
+func describeEnv(env map[string]string) string {
+	pairs := make([]string, 0, len(env))
+	for name, value := range env {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// assertEnvIndependent ensures that the standard output of the run with the
+// environment variant is identical to the test's output once normalized,
+// reporting the variant and the first line which differs.
+func assertEnvIndependent(first, second string, variant map[string]string, replacements []Replacement) error {
+	run := fmt.Sprintf("run with environment [%s]", describeEnv(variant))
+	if err := compareRuns(first, second, run, replacements); err != nil {
+		return NewPrefixedError("must be independent of the environment", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_assertEnvIndependent(t *testing.T) {
+	variant := map[string]string{"TZ": "Asia/Tokyo", "LANG": "C"}
+	tests := []struct {
+		name          string
+		first, second string
+		normalize     []Replacement
+		err           string
+	}{
+		{
+			name:   "Passes when the outputs are identical",
+			first:  "cluster started\n",
+			second: "cluster started\n",
+		},
+		{
+			name:      "Passes when the outputs are identical once normalized",
+			first:     "started at 10:00\n",
+			second:    "started at 19:00\n",
+			normalize: []Replacement{{Pattern: `\d{2}:\d{2}`, Replace: "<time>"}},
+		},
+		{
+			name:   "Reports the environment and the line which differs",
+			first:  "cluster started\nat 10:00\n",
+			second: "cluster started\nat 19:00\n",
+			err:    "run with environment [LANG=C TZ=Asia/Tokyo] differs: must match lines\nline 1 got \"at 19:00\" want \"at 10:00\"",
+		},
+		{
+			name:   "Reports the output of the variant when the test's output is empty",
+			second: "warning: unknown locale\n",
+			err:    "run with environment [LANG=C TZ=Asia/Tokyo] differs: must match lines\nline 0 \"warning: unknown locale\" is unexpected, want 0 lines",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertEnvIndependent(tt.first, tt.second, variant, tt.normalize)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertEnvIndependent() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertEnvIndependent() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_mergeEnv(t *testing.T) {
+	env := map[string]string{"LANG": "en_US.UTF-8", "REGION": "us-east-1"}
+	got := mergeEnv(env, map[string]string{"LANG": "C", "TZ": "UTC"})
+	want := map[string]string{"LANG": "C", "REGION": "us-east-1", "TZ": "UTC"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeEnv() = %v, want %v", got, want)
+	}
+	if env["LANG"] != "en_US.UTF-8" {
+		t.Error("mergeEnv() modified the original environment")
+	}
+}
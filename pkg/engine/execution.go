@@ -19,6 +19,7 @@ package engine
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -27,9 +28,13 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/elastic/testcli/pkg/engine/teststorage"
 )
@@ -37,26 +42,104 @@ import (
 const (
 	// Red fail text
 	failRed = "\x1b[31;1mFAIL\x1b[0m"
+	// Yellow warn text
+	warnYellow = "\x1b[33;1mWARN\x1b[0m"
 
 	defaultCooldownPeriod = 100 * time.Millisecond
+
+	// Time the output of a command is still read for after it exits or is
+	// killed, while a process it started keeps the pipes open.
+	outputWaitDelay = time.Second
+
+	// Separates a dynamic argument key from its fallback value.
+	dynamicArgFallback = "|="
+
+	// Prefixes a dynamic argument which joins the values of several keys.
+	dynamicArgConcat = "concat:"
 )
 
 // ExecuteTests takes in the testing.T and a list of integration tests to run.
-func ExecuteTests(t *testing.T, tests Tests) {
+// The options configure the execution of the whole suite.
+func ExecuteTests(t *testing.T, tests Tests, opts ...Option) {
+	ExecuteTestsContext(context.Background(), t, tests, opts...)
+}
+
+// ExecuteTestsContext is like ExecuteTests, but once the context is done the
+// running commands are killed and the tests which haven't started are skipped.
+func ExecuteTestsContext(ctx context.Context, t *testing.T, tests Tests, opts ...Option) {
 	var storage = teststorage.GetInMemory()
+	var cfg = newConfig(opts...)
+	cfg.ctx = ctx
+
+	deps, err := newDependencies(tests)
+	if err != nil {
+		t.Fatalf("[%s]: %s", cfg.failLabel(), err)
+	}
+	cfg.deps = deps
+
+	if cfg.failureCache != "" {
+		failures, err := newFailureCache(cfg.failureCache, cfg.runFailedOnly, tests)
+		if err != nil {
+			t.Fatalf("[%s]: failed reading the failure cache: %s", cfg.failLabel(), err)
+		}
+		cfg.failures = failures
+
+		// Cleanups run once all the tests have finished, including the
+		// parallel ones.
+		t.Cleanup(func() {
+			if err := failures.save(); err != nil {
+				t.Errorf("[%s]: failed writing the failure cache: %s", cfg.failLabel(), err)
+			}
+		})
+	}
+
+	if budget := cfg.retryBudget; budget != nil {
+		t.Cleanup(func() { t.Logf("retry budget: %s", budget) })
+	}
 
-	for testN, tt := range tests {
+	if cfg.requireVersion != nil {
+		if err := checkVersion(ctx, *cfg.requireVersion, tests); err != nil {
+			t.Fatalf("[%s]: %s", cfg.failLabel(), err)
+		}
+	}
+
+	ordered := deps.order(tests)
+	for testN, tt := range ordered {
+		var started bool
 		t.Run(tt.Name, func(subTest *testing.T) {
-			executeTestCase(subTest, testN, tt, storage)
+			started = true
+			if cfg.failures.skip(tt.Name) {
+				cfg.deps.finish(tt.Name, false)
+				// The report is added once Skipf has marked the test as
+				// skipped.
+				if cfg.report != nil {
+					defer cfg.report.add(subTest, TestReport{Name: tt.Name, n: testN})
+				}
+				subTest.Skipf("[Test %d]: passed on the last run", testN)
+			}
+			defer func() { cfg.failures.record(tt.Name, subTest.Failed()) }()
 
-			// Always delay each test case 100ms*0-10 so that the tests don't choke
-			// the client machine where the tests are running.
-			<-time.After(defaultCooldownPeriod*time.Duration(rand.Intn(9)+1) + tt.WaitBeforeRun)
+			executeTestCase(subTest, testN, tt, storage, cfg)
+
+			// Delay each test case by the cooldown so that the tests don't choke
+			// the client machine where the tests are running. There's no test
+			// to protect after the last one.
+			var cooldown time.Duration
+			if testN < len(ordered)-1 {
+				cooldown = cfg.cooldownPeriod()
+			}
+			cfg.wait(cooldown + tt.WaitBeforeRun)
 		})
+
+		// Tests which are filtered out never start, so the tests depending on
+		// them must not wait for them.
+		if !started {
+			cfg.deps.finish(tt.Name, false)
+		}
 	}
 }
 
-func executeTestCase(t *testing.T, testN int, tt Test, storage teststorage.Storage) {
+func executeTestCase(t *testing.T, testN int, tt Test, storage teststorage.Storage, cfg config) {
 	// The first part of the command's arguments, having the config slice
 	// first and then appending the positional command's arguments or flags.
 	//
@@ -69,55 +152,490 @@ func executeTestCase(t *testing.T, testN int, tt Test, storage teststorage.Stora
 	if tt.Parallel {
 		t.Parallel()
 	}
+	// The test counts as running until its command has been asserted, which
+	// scales the cooldown of the other tests when it's adaptive.
+	defer cfg.start()()
+
+	// The tests this test depends on have already run, and must have passed.
+	defer func() { cfg.deps.finish(tt.Name, !t.Failed() && !t.Skipped()) }()
+	defer func() { storage.Set(CompletedAtKey(tt.Name), cfg.clock.Now().Format(time.RFC3339Nano)) }()
+
+	// The details are filled once the command has run.
+	entry := TestReport{Name: tt.Name, n: testN}
+	if cfg.report != nil {
+		defer func() { cfg.report.add(t, entry) }()
+	}
+	if err := cfg.ctx.Err(); err != nil {
+		t.Skipf("[Test %d]: not started: %s", testN, err)
+	}
+	if err := cfg.deps.check(tt.DependsOn); err != nil {
+		t.Skipf("[Test %d]: %s", testN, err)
+	}
+	if missing := missingEnv(tt.RequireEnv); len(missing) > 0 {
+		t.Skipf("[Test %d]: required environment variables are not set: %s", testN, strings.Join(missing, ", "))
+	}
+
+	if cfg.namespacedStorage {
+		storage = teststorage.Namespaced(storage, storageNamespace(tt.Name))
+	}
 
 	dynamicArgs, err := parseDynamicArguments(tt.Args.DynamicArgs, storage)
 	if err != nil {
-		t.Fatalf("[Test %d][%s]: %s", testN, failRed, err)
+		t.Fatalf("[Test %d][%s]: %s", testN, cfg.failLabel(), err)
 	}
 
-	var args = append(
+	var args = append(append(
 		append(tt.Args.Config, tt.Args.Args...), dynamicArgs...,
-	)
+	), tt.Args.TrailingArgs...)
+
+	placeholders := make(map[string]string)
+	// The config file is removed along with the test's temporary directory.
+	if tt.ConfigFile != nil {
+		path, err := tt.ConfigFile.write(t.TempDir())
+		if err != nil {
+			t.Fatalf("[Test %d][%s]: %s", testN, cfg.failLabel(), err)
+		}
+		placeholders[ConfigFilePlaceholder] = path
+	}
+	if tt.TempDir {
+		dir, err := makeTempDir(t, tt.KeepTempDirOnFailure)
+		if err != nil {
+			t.Fatalf("[Test %d][%s]: %s", testN, cfg.failLabel(), err)
+		}
+		placeholders[TempDirPlaceholder] = dir
+	}
+	args = expandPlaceholders(args, placeholders)
+	env := expandEnvPlaceholders(tt.Env, placeholders)
+	fileAssertions := expandFilePlaceholders(tt.FileAssertions, placeholders)
+	workingDir := expandPlaceholders([]string{tt.WorkingDir}, placeholders)[0]
+	preCommands := expandCommandPlaceholders(tt.PreCommands, placeholders)
 
 	if tt.Binary == "" {
-		t.Fatalf("[Test %d][%s]: binary not set, please set a binary name", testN, failRed)
+		t.Fatalf("[Test %d][%s]: binary not set, please set a binary name", testN, cfg.failLabel())
 	}
 	binary := tt.Binary
 
 	if tt.FindBinary {
-		found, err := FindBinaryPath(".", binary)
+		ctx := cfg.ctx
+		if tt.FindBinaryTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, tt.FindBinaryTimeout)
+			defer cancel()
+		}
+		found, err := FindBinaryPathContext(ctx, tt.SearchRoot, binary)
 		if err != nil {
 			t.Fatal(err)
 		}
+		found = filepath.Join(tt.SearchRoot, found)
+		// The binary is found relative to the current directory, which
+		// isn't the one the command runs in when the WorkingDir is set.
+		if workingDir != "" {
+			if found, err = filepath.Abs(found); err != nil {
+				t.Fatal(err)
+			}
+		}
 		binary = found
 	}
 
-	stdout, stderr, err := runCommand(
-		t, binary, testN, args, tt.Args.Interactive, tt.Assert.WantErr,
-	)
+	if tt.WaitForReady != nil {
+		timeout := tt.ReadyTimeout
+		if timeout == 0 {
+			timeout = defaultReadyTimeout
+		}
+		backoff := Backoff{Base: defaultReadyInterval, Max: defaultReadyMaxInterval, Jitter: defaultReadyJitter}
+		if err := waitForReady(cfg.ctx, cfg.clock, tt.WaitForReady, timeout, backoff); err != nil {
+			t.Fatalf("[Test %d][%s]: %s", testN, cfg.failLabel(), err)
+		}
+	}
+
+	if err := runPreCommands(cfg.ctx, preCommands, workingDir); err != nil {
+		t.Fatalf("[Test %d][%s]: %s", testN, cfg.failLabel(), err)
+	}
+
+	command := redactPasswordFlag(strings.Join(append([]string{binary}, args...), " "))
+	if cfg.verbose {
+		wd := workingDir
+		if wd == "" {
+			wd, _ = os.Getwd()
+		}
+		resolved, lookErr := exec.LookPath(binary)
+		if lookErr != nil {
+			resolved = lookErr.Error()
+		}
+		t.Logf("[Test %d]: binary: %s", testN, resolved)
+		t.Logf("[Test %d]: command: %s", testN, command)
+		t.Logf("[Test %d]: working directory: %s", testN, wd)
+		if len(env) > 0 {
+			t.Logf("[Test %d]: environment: %s", testN, describeEnv(redactEnv(env)))
+		}
+		if len(tt.UnsetEnv) > 0 {
+			t.Logf("[Test %d]: unset environment: %s", testN, strings.Join(tt.UnsetEnv, " "))
+		}
+	}
+
+	inv := invocation{
+		bin: binary, args: args, dir: workingDir, interactive: tt.Args.Interactive,
+		interactiveDelay: tt.Args.InteractiveDelay,
+		clock:            cfg.clock,
+		env:              commandEnv(os.Environ(), tt.UnsetEnv, env),
+		onOutput:         tt.OnOutput,
+	}
+	inv.stdoutTee, inv.stderrTee = tt.OutputWriter, tt.ErrorWriter
+	if cfg.liveOutput {
+		if inv.stdoutTee == nil {
+			inv.stdoutTee = os.Stdout
+		}
+		if inv.stderrTee == nil {
+			inv.stderrTee = os.Stderr
+		}
+	}
+
+	// Runs the command, ensuring its assertions and running its callbacks.
+	// The details of the last execution are kept for the summary.
+	var last execution
+	var stable *stableStorage
+	if tt.StableRetryWrites {
+		stable = newStableStorage(storage)
+		storage = stable
+	}
+	// The output of the last attempt is logged when the test fails, including
+	// when it fails before the attempt returns.
+	var lastStdout, lastStderr *bytes.Buffer
+	if cfg.outputOnFailure {
+		defer func() { logOutputOnFailure(t, testN, lastStdout, lastStderr) }()
+	}
+	attempt := func() (*bytes.Buffer, *bytes.Buffer, []error) {
+		start := cfg.clock.Now()
+		if stable != nil {
+			stable.nextAttempt()
+		}
+		var combined syncBuffer
+		if needsCombined(tt.CallbackStreams) {
+			inv.combined = &combined
+		}
+		ctx := cfg.ctx
+		if tb := tt.Assert.Timeout; tb != nil {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, tb.Within)
+			defer cancel()
+		}
+		var written int
+		inv.written = &written
+		stdout, stderr, err := runCommand(ctx, inv)
+		lastStdout, lastStderr = stdout, stderr
+		combinedOut := combined.Buffer()
+		view := executionStorage{Storage: storage, execution: execution{
+			binary:   binary,
+			args:     args,
+			exitCode: exitCode(err),
+			duration: cfg.clock.Now().Sub(start),
+			env:      inv.env,
+		}}
+		last = view.execution
+		if cfg.verbose {
+			t.Logf("[Test %d]: exit code: %d, duration: %s", testN,
+				view.execution.exitCode, view.execution.duration,
+			)
+		}
+
+		if tt.OutputEncoding != "" {
+			var decodeErr error
+			stdout, stderr, decodeErr = decodeOutput(tt.OutputEncoding, stdout, stderr)
+			if decodeErr != nil {
+				t.Fatalf("[Test %d][%s]: %s", testN, cfg.failLabel(), decodeErr)
+			}
+			if inv.combined != nil {
+				combinedOut, _, decodeErr = decodeOutput(tt.OutputEncoding, combinedOut, new(bytes.Buffer))
+				if decodeErr != nil {
+					t.Fatalf("[Test %d][%s]: %s", testN, cfg.failLabel(), decodeErr)
+				}
+			}
+		}
+
+		lastStdout, lastStderr = stdout, stderr
+
+		if must := tt.Assert.Must; cfg.verbose && must.DedupeLines {
+			out := stdout.String()
+			if removed := len(splitLines(out)) - len(splitLines(dedupeLines(out, must.DedupeGlobal))); removed > 0 {
+				t.Logf("[Test %d]: removed %d duplicate lines of the standard output", testN, removed)
+			}
+		}
+
+		// Ensures the assertions.
+		var errs []error
+		if err := tt.Assert.ensure(stdout, stderr, err, view, command, time.Now); err != nil {
+			errs = append(errs, err)
+		}
+		if err := assertTimeout(tt.Assert.Timeout, view.execution.duration, err, stderr.String()); err != nil {
+			errs = append(errs, err)
+		}
+		if tt.Assert.AllInputConsumed && written < len(inv.interactive) {
+			errs = append(errs, fmt.Errorf(
+				"input: only %d of %d interactive lines were written before the command exited",
+				written, len(inv.interactive),
+			))
+		}
+		if secrets := tt.Assert.MustNotEchoInput; len(secrets) > 0 {
+			if err := assertNoEcho(secrets, inv.interactive, written, stdout.String(), stderr.String()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := assertFiles(workingDir, fileAssertions); err != nil {
+			errs = append(errs, err)
+		}
+
+		// Stores the raw output, which is the simplest form of a callback.
+		if tt.StoreStdoutKey != "" {
+			storage.Set(tt.StoreStdoutKey, stdout.String())
+		}
+		if tt.StoreStderrKey != "" {
+			storage.Set(tt.StoreStderrKey, stderr.String())
+		}
+
+		// The callbacks are used to populate the storage on runtime.
+		// Decoding happens inside a tailored function which parses the []byte output
+		// to a specific data structure, which populates result[key].
+		outputs := map[Stream][]byte{Stdout: stdout.Bytes(), Stderr: stderr.Bytes(), Combined: combinedOut.Bytes()}
+		if err := tt.Callbacks.RunStreams(outputs, tt.CallbackStreams, view); err != nil {
+			errs = append(errs, err)
+		}
+		if stable != nil {
+			if err := stable.err(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return stdout, stderr, errs
+	}
+
+	// Failed attempts are retried up to the number of retries, as long as the
+	// standard error matches the retry conditions.
+	stdout, stderr, errs := attempt()
+	backoff := Backoff{Base: tt.RetryBackoff, Max: tt.RetryMaxBackoff, Jitter: tt.RetryJitter}
+	if cfg.verbose && len(errs) > 0 && tt.Retries > 0 {
+		t.Logf("[Test %d]: retry schedule: %v, jitter: %v", testN, backoff.Schedule(tt.Retries), tt.RetryJitter)
+	}
+	for retry := 0; len(errs) > 0 && retry < tt.Retries; retry++ {
+		if !shouldRetry(tt.RetryOnStderr, stderr.String()) {
+			break
+		}
+		if !cfg.retryBudget.take() {
+			t.Logf("[Test %d]: not retrying, the suite retry budget is exhausted: %s", testN, cfg.retryBudget)
+			break
+		}
+
+		delay := backoff.delay(retry, rand.Float64)
+		t.Logf("[Test %d]: attempt %d failed, retrying in %s", testN, retry+1, delay)
+		if cfg.retryBudget != nil {
+			t.Logf("[Test %d]: %d retries remaining in the suite retry budget", testN, cfg.retryBudget.remaining())
+		}
+		cfg.wait(delay)
+		stdout, stderr, errs = attempt()
+	}
+
+	// The exit code is recorded once the retries are done, so the later
+	// tests only see the exit code of the last attempt.
+	storage.Set(ExitCodeKey(tt.Name), strconv.Itoa(last.exitCode))
+
+	// Runs the command again, without asserting or running the callbacks,
+	// returning its standard output and error.
+	rerun := func(inv invocation) (*bytes.Buffer, *bytes.Buffer) {
+		again, againErr, _ := runCommand(cfg.ctx, inv)
+		if tt.OutputEncoding != "" {
+			if decoded, decodedErr, err := decodeOutput(tt.OutputEncoding, again, againErr); err == nil {
+				again, againErr = decoded, decodedErr
+			}
+		}
+		return again, againErr
+	}
+
+	// The command is run again to compare the output of both runs.
+	if tt.AssertIdempotent && len(errs) == 0 {
+		again, _ := rerun(inv)
+		if err := assertIdempotent(stdout.String(), again.String(), tt.Normalize); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if tt.Repeat > 0 && len(errs) == 0 {
+		sizes := []int{stdout.Len()}
+		for i := 0; i < tt.Repeat; i++ {
+			again, _ := rerun(inv)
+			sizes = append(sizes, again.Len())
+		}
+		if err := assertOutputGrowth(sizes, tt.RepeatGrowthTolerance); err != nil {
+			errs = append(errs, err)
+		}
+	}
 
-	// Ensures the assertions.
-	var errs []error
-	if err := tt.Assert.Ensure(stdout, stderr, err, storage,
-		redactPasswordFlag(strings.Join(append([]string{binary}, args...), " ")),
-	); err != nil {
-		errs = append(errs, err)
+	for _, variant := range tt.EnvVariants {
+		if len(errs) > 0 {
+			break
+		}
+		variant = expandEnvPlaceholders(variant, placeholders)
+		other := inv
+		other.env = commandEnv(os.Environ(), tt.UnsetEnv, mergeEnv(env, variant))
+		again, _ := rerun(other)
+		if err := assertEnvIndependent(stdout.String(), again.String(), variant, tt.Normalize); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	// The callbacks are used to populate the storage on runtime.
-	// Decoding happens inside a tailored function which parses the []byte output
-	// to a specific data structure, which populates result[key].
-	if err := tt.Callbacks.Run(stdout.Bytes(), storage); err != nil {
-		errs = append(errs, err)
+	for _, c := range tt.CompareOutputSize {
+		if len(errs) > 0 {
+			break
+		}
+		other := inv
+		other.args = expandPlaceholders(c.Args, placeholders)
+		again, againErr := rerun(other)
+		first := c.outputSize(stdout.Len(), stderr.Len())
+		if err := assertOutputSize(c, first, c.outputSize(again.Len(), againErr.Len())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	entry.Command, entry.ExitCode, entry.Duration = command, last.exitCode, last.duration
+	entry.Stdout = elide(redactPasswordFlag(stdout.String()), cfg.outputLimit)
+	entry.Stderr = elide(redactPasswordFlag(stderr.String()), cfg.outputLimit)
+
+	// Unmet Should assertions are logged as warnings, without failing the test.
+	// Like the Must assertions, they can read the details of the execution.
+	view := executionStorage{Storage: storage, execution: last}
+	if err := tt.Assert.warnings(stdout, stderr, view, time.Now); err != nil {
+		t.Log(elideOutputs(
+			NewPrefixedError(fmt.Sprintf("[Test %d][%s]", testN, cfg.warnLabel()), err).Error(),
+			cfg.outputLimit, stdout.String(), stderr.String(),
+		))
+		entry.Warnings = append(entry.Warnings, redactPasswordFlag(
+			elideOutputs(err.Error(), cfg.outputLimit, stdout.String(), stderr.String()),
+		))
 	}
 
 	// Make the test fail.
 	if len(errs) > 0 {
-		t.Error(NewPrefixedError(
-			fmt.Sprintf("[Test %d][%s]", testN, failRed),
-			errors.Join(errs...),
+		t.Error(elideOutputs(
+			NewPrefixedError(fmt.Sprintf("[Test %d][%s]", testN, cfg.failLabel()), errors.Join(errs...)).Error(),
+			cfg.outputLimit, stdout.String(), stderr.String(),
 		))
 	}
+
+	if cfg.logOnSuccess && !t.Failed() {
+		t.Logf("[Test %d]: passed: %s, exit code: %d, duration: %s", testN,
+			command, last.exitCode, last.duration,
+		)
+	}
+}
+
+// runPreCommands runs each of the commands in order, stopping on the first
+// one which fails.
+func runPreCommands(ctx context.Context, commands [][]string, dir string) error {
+	for _, command := range commands {
+		if len(command) == 0 {
+			continue
+		}
+
+		_, stderr, err := runCommand(ctx, invocation{bin: command[0], args: command[1:], dir: dir})
+		if err != nil {
+			return fmt.Errorf("pre command \"%s\" failed: %w, stderr = %s",
+				redactPasswordFlag(strings.Join(command, " ")), err, stderr,
+			)
+		}
+	}
+	return nil
+}
+
+// logOutputOnFailure logs the complete standard output and error when the
+// test has failed, whatever the stage it failed at, with the passwords
+// redacted. Nothing is logged when the command didn't run.
+func logOutputOnFailure(t *testing.T, testN int, stdout, stderr *bytes.Buffer) {
+	if !t.Failed() || stdout == nil {
+		return
+	}
+	t.Logf("[Test %d]: standard output:\n%s", testN, redactPasswordFlag(stdout.String()))
+	t.Logf("[Test %d]: standard error:\n%s", testN, redactPasswordFlag(stderr.String()))
+}
+
+// missingEnv returns the environment variables which are unset or empty.
+func missingEnv(names []string) []string {
+	var missing []string
+	for _, name := range names {
+		if os.Getenv(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// commandEnv returns the environment without the unset variables, followed by
+// the overrides sorted by name.
+func commandEnv(environ, unset []string, overrides map[string]string) []string {
+	removed := make(map[string]bool, len(unset)+len(overrides))
+	for _, name := range unset {
+		removed[name] = true
+	}
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		removed[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make([]string, 0, len(environ)+len(names))
+	for _, kv := range environ {
+		if name, _, _ := strings.Cut(kv, "="); !removed[name] {
+			env = append(env, kv)
+		}
+	}
+	for _, name := range names {
+		env = append(env, name+"="+overrides[name])
+	}
+	return env
+}
+
+// shouldRetry returns true when there are no retry conditions, or when the
+// standard error contains any of them.
+func shouldRetry(conditions []string, stderr string) bool {
+	if len(conditions) == 0 {
+		return true
+	}
+	for _, condition := range conditions {
+		if strings.Contains(stderr, condition) {
+			return true
+		}
+	}
+	return false
+}
+
+// unsafeNamespaceChars matches the characters which aren't kept in a storage
+// namespace.
+var unsafeNamespaceChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// storageNamespace returns the storage namespace of the test name.
+func storageNamespace(name string) string {
+	return unsafeNamespaceChars.ReplaceAllString(name, "_")
+}
+
+// concatDynamicArgument joins the values of the keys in a spec of the form
+// "sep=-:keyA,keyB", where the separator is optional.
+func concatDynamicArgument(spec string, storage teststorage.Storage) (string, error) {
+	var sep string
+	if rest, ok := strings.CutPrefix(spec, "sep="); ok {
+		var keys string
+		var found bool
+		if sep, keys, found = strings.Cut(rest, ":"); !found {
+			return "", fmt.Errorf("concat separator of \"%s\" must be followed by \":\" and the keys", dynamicArgConcat+spec)
+		}
+		spec = keys
+	}
+
+	var values []string
+	for _, key := range strings.Split(spec, ",") {
+		value, ok := storage.Get(key)
+		if !ok {
+			return "", fmt.Errorf("failed to obtain value of key %s", key)
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, sep), nil
 }
 
 func parseDynamicArguments(dynamicArgs []string, storage teststorage.Storage) ([]string, error) {
@@ -136,50 +654,211 @@ func parseDynamicArguments(dynamicArgs []string, storage teststorage.Storage) ([
 			continue
 		}
 
+		if spec, ok := strings.CutPrefix(key, dynamicArgConcat); ok {
+			value, err := concatDynamicArgument(spec, storage)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		// A key in the form of "key|=fallback" uses the fallback value when
+		// the key isn't found in the storage.
+		key, fallback, hasFallback := strings.Cut(key, dynamicArgFallback)
 		value, ok := storage.Get(key)
-		if !ok {
+		if !ok && !hasFallback {
 			return nil, fmt.Errorf("failed to obtain value of key %s", key)
 		}
+		if !ok {
+			value = fallback
+		}
 		result = append(result, value)
 	}
 	return result, nil
 }
 
-func runCommand(t *testing.T, bin string, testN int, args, interactive []string, wantErr bool) (*bytes.Buffer, *bytes.Buffer, error) {
-	// NTH?: CommandContext might be interesting here
-	var cmd = exec.Command(bin, args...)
-	var stderr, stdout = bytes.Buffer{}, bytes.Buffer{}
-	cmd.Stderr, cmd.Stdout = &stderr, &stdout
-	cmd.Env = append(cmd.Env, os.Environ()...)
+// invocation defines how a command is run.
+type invocation struct {
+	bin  string
+	args []string
+
+	// Directory the command is run in, the current directory when empty.
+	dir string
 
-	if len(interactive) == 0 {
-		return &stdout, &stderr, cmd.Run()
+	// Lines written to the standard input, which is closed afterwards.
+	interactive []string
+
+	// Pause before writing each of the interactive lines but the first.
+	interactiveDelay time.Duration
+
+	// Clock which times the interactiveDelay, the real clock when nil.
+	clock Clock
+
+	// When set, receives the number of interactive lines which were written
+	// before the command exited. Lines which the operating system buffered
+	// but the command didn't read count as written.
+	written *int
+
+	// Environment of the command, inherited from the current process when nil.
+	env []string
+
+	// When set, it's called with each line of the standard output as it's
+	// read, from a single goroutine.
+	onOutput func(line string)
+
+	// When set, receive a copy of each stream as it's read. Their errors
+	// are ignored so they can't affect the captured output. They can be the
+	// same writer, since their writes are synchronized.
+	stdoutTee, stderrTee io.Writer
+
+	// When set, receives the output of both streams in the order it's read.
+	// Its writes are synchronized, so it doesn't need to be safe for
+	// concurrent use.
+	combined io.Writer
+}
+
+func runCommand(ctx context.Context, inv invocation) (*bytes.Buffer, *bytes.Buffer, error) {
+	// The command is killed once the context is done.
+	var cmd = exec.CommandContext(ctx, inv.bin, inv.args...)
+	cmd.Env = inv.env
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
 	}
+	cmd.Dir = inv.dir
+	// A process started by the command, which inherits its output, can keep
+	// the pipes open after the command exits or is killed. They're closed
+	// after the delay, so it can't outlast the context.
+	cmd.WaitDelay = outputWaitDelay
 
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		t.Fatalf("[Test %d][%s]: Command %s: failed to create stdin pipe", testN, failRed, bin)
+	// The streams are copied from different goroutines, so the writers which
+	// may be shared by both, such as the same buffer used as both tees, are
+	// synchronized with a mutex.
+	var stdout, stderr syncBuffer
+	var stdoutW, stderrW io.Writer = &stdout, &stderr
+	if inv.combined != nil {
+		combined := syncWriter{mu: new(sync.Mutex), w: inv.combined}
+		stdoutW, stderrW = io.MultiWriter(stdoutW, combined), io.MultiWriter(stderrW, combined)
+	}
+	var teeMu sync.Mutex
+	if inv.stdoutTee != nil {
+		stdoutW = io.MultiWriter(stdoutW, ignoreErrors{syncWriter{mu: &teeMu, w: inv.stdoutTee}})
+	}
+	if inv.stderrTee != nil {
+		stderrW = io.MultiWriter(stderrW, ignoreErrors{syncWriter{mu: &teeMu, w: inv.stderrTee}})
+	}
+	var lines *lineWriter
+	if inv.onOutput != nil {
+		lines = &lineWriter{fn: inv.onOutput}
+		stdoutW = io.MultiWriter(stdoutW, lines)
+	}
+	// The output is copied from the pipes by a goroutine per stream into
+	// synchronized buffers, so it can be safely inspected while the command
+	// is still running.
+	cmd.Stdout, cmd.Stderr = stdoutW, stderrW
+
+	var stdin io.WriteCloser
+	var err error
+	if len(inv.interactive) > 0 {
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			return &bytes.Buffer{}, &bytes.Buffer{}, err
+		}
+	}
+
+	if err := cmd.Start(); err != nil {
+		return &bytes.Buffer{}, &bytes.Buffer{}, err
 	}
-	defer stdin.Close()
 
-	if err := cmd.Start(); (err != nil) != wantErr {
-		printableArgs := redactPasswordFlag(strings.Join(args, " "))
-		t.Errorf("[Test %d][%s]: Command %s %v error = %v, wantErr = %v, stderr = %v", testN, failRed, bin, printableArgs, err, wantErr, stderr.String())
-		return &stdout, &stderr, err
+	if stdin != nil {
+		// Writing fails once the command has exited without reading the
+		// rest of the lines, which aren't written.
+		clock := inv.clock
+		if clock == nil {
+			clock = realClock{}
+		}
+		var written int
+		for i, line := range inv.interactive {
+			if i > 0 && !sleepContext(ctx, clock, inv.interactiveDelay) {
+				break
+			}
+			if _, err := io.WriteString(stdin, fmt.Sprintln(line)); err != nil {
+				break
+			}
+			written++
+		}
+		stdin.Close()
+		if inv.written != nil {
+			*inv.written = written
+		}
 	}
 
-	for _, line := range interactive {
-		_, _ = io.WriteString(stdin, fmt.Sprintln(line))
+	// Wait returns once the output is copied, or the WaitDelay elapsed.
+	err = cmd.Wait()
+	if lines != nil {
+		lines.flush()
 	}
+	return stdout.Buffer(), stderr.Buffer(), err
+}
 
-	return &stdout, &stderr, cmd.Wait()
+// sleepContext pauses for the duration on the clock, returning false when the
+// context is done before it has elapsed.
+func sleepContext(ctx context.Context, clock Clock, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	// Checked first, since a fake clock's channel may be ready already.
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case <-clock.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// exitCode returns the exit code of a command from the error returned when
+// running it, which is how every exit code assertion obtains it. It relies on
+// os.ProcessState.ExitCode rather than the platform specific wait status, so
+// it behaves the same on every platform. When the command couldn't be run, or
+// it didn't exit on its own because it was terminated by a signal, -1 is
+// returned.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ProcessState == nil {
+		return -1
+	}
+
+	// ExitCode is -1 when the process was terminated by a signal.
+	return exitErr.ProcessState.ExitCode()
 }
 
 // FindBinaryPath executes a reverse walk to find the ecl binary on the parent path.
+// The walk stops at the repository root, the first directory with a ".git"
+// entry, or at the filesystem root, returning an error when the binary isn't
+// found.
 func FindBinaryPath(p, binary string) (string, error) {
+	return FindBinaryPathContext(context.Background(), p, binary)
+}
+
+// FindBinaryPathContext is like FindBinaryPath, but the walk is aborted with an
+// error once the context is done, bounding the walk on huge repositories.
+func FindBinaryPathContext(ctx context.Context, p, binary string) (string, error) {
 	var binaryPath string
 	p, _ = filepath.Abs(p)
 	err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("stopped looking for binary \"%s\" in %s: %w", binary, path, ctxErr)
+		}
+		// The paths which can't be read are ignored, they can't be the binary.
+		if err != nil {
+			return nil
+		}
 		if info.Name() == binary && !info.IsDir() {
 			relPath, err := filepath.Rel(p, path)
 			if err != nil {
@@ -195,14 +874,67 @@ func FindBinaryPath(p, binary string) (string, error) {
 	}
 
 	if binaryPath == "" {
-		binaryPath, err = FindBinaryPath(filepath.Dir(p), binary)
+		parent := filepath.Dir(p)
+		if isRepositoryRoot(p) || parent == p {
+			return "", fmt.Errorf("binary \"%s\" not found up to %s", binary, p)
+		}
+
+		binaryPath, err = FindBinaryPathContext(ctx, parent, binary)
 		return filepath.Join("..", binaryPath), err
 	}
 
 	return binaryPath, nil
 }
 
+// isRepositoryRoot returns true when the directory is the root of a git
+// repository.
+func isRepositoryRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
 func redactPasswordFlag(cmd string) string {
 	var re = regexp.MustCompile(`(?m)\-\-pass?[ =]([^ ]+)`)
 	return re.ReplaceAllString(cmd, "--pass [REDACTED]")
 }
+
+// redactEnv returns a copy of the environment variables with their values
+// redacted, since they often hold credentials such as API keys.
+func redactEnv(env map[string]string) map[string]string {
+	redacted := make(map[string]string, len(env))
+	for name := range env {
+		redacted[name] = "[REDACTED]"
+	}
+	return redacted
+}
+
+// elideOutputs replaces the occurrences of each of the outputs in the message
+// with its elided version, so long outputs don't flood the test logs.
+func elideOutputs(msg string, limit int, outputs ...string) string {
+	// Replaces the longest outputs first, in case an output contains another.
+	sort.Slice(outputs, func(i, j int) bool { return len(outputs[i]) > len(outputs[j]) })
+	for _, out := range outputs {
+		if elided := elide(out, limit); elided != out {
+			msg = strings.ReplaceAll(msg, out, elided)
+		}
+	}
+	return msg
+}
+
+// elide shortens s to roughly the limit by keeping its head and tail around
+// an elision marker. A limit <= 0 returns s as is.
+func elide(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+
+	// Moves the cut points to the start of a rune, so it's not split in half.
+	head, tail := limit/2, len(s)-limit/2
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	for tail < len(s) && !utf8.RuneStart(s[tail]) {
+		tail++
+	}
+	return fmt.Sprintf("%s\n[... %d bytes elided ...]\n%s", s[:head], tail-head, s[tail:])
+}
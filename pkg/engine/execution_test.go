@@ -18,11 +18,18 @@
 package engine
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/elastic/testcli/pkg/engine/enginetest"
 	"github.com/elastic/testcli/pkg/engine/teststorage"
 )
 
@@ -66,6 +73,64 @@ func TestFindEclPath(t *testing.T) {
 	}
 }
 
+func TestFindBinaryPath_repositoryRoot(t *testing.T) {
+	repo := t.TempDir()
+	for _, dir := range []string{".git", "build/bin", "cmd/cli"} {
+		if err := os.MkdirAll(filepath.Join(repo, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(repo, "build/bin/cli"), []byte("some"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The search root is walked first.
+	got, err := FindBinaryPath(filepath.Join(repo, "build"), "cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("bin", "cli"); got != want {
+		t.Errorf("FindBinaryPath() = %v, want %v", got, want)
+	}
+
+	// The walk stops at the repository root.
+	_, err = FindBinaryPath(filepath.Join(repo, "cmd/cli"), "anunexistingbinary")
+	if want := "binary \"anunexistingbinary\" not found up to " + repo; err == nil || err.Error() != want {
+		t.Errorf("FindBinaryPath() error = %v, want %v", err, want)
+	}
+}
+
+func TestFindBinaryPathContext(t *testing.T) {
+	repo := t.TempDir()
+	for _, dir := range []string{".git", "build/bin"} {
+		if err := os.MkdirAll(filepath.Join(repo, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(repo, "build/bin/cli"), []byte("some"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FindBinaryPathContext(context.Background(), filepath.Join(repo, "build"), "cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("bin", "cli"); got != want {
+		t.Errorf("FindBinaryPathContext() = %v, want %v", got, want)
+	}
+
+	// The walk is aborted once the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = FindBinaryPathContext(ctx, filepath.Join(repo, "build"), "cli")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("FindBinaryPathContext() error = %v, want %v", err, context.Canceled)
+	}
+	if want := "stopped looking for binary \"cli\""; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("FindBinaryPathContext() error = %v, want %v", err, want)
+	}
+}
+
 func Test_redactPasswordFlag(t *testing.T) {
 	type args struct {
 		cmd string
@@ -102,6 +167,7 @@ func Test_parseDynamicArguments(t *testing.T) {
 	}
 	safemap := teststorage.NewSafeMap()
 	safemap.Set("akey", "avalue")
+	safemap.Set("region", "us-east-1")
 	tests := []struct {
 		name string
 		args args
@@ -116,6 +182,14 @@ func Test_parseDynamicArguments(t *testing.T) {
 			},
 			want: []string{"avalue", "stripped_key"},
 		},
+		{
+			name: "Uses the fallback value for unexisting keys",
+			args: args{
+				dynamicArgs: []string{"akey|=unused", "unexisting key|=fallback", "empty|="},
+				storage:     safemap,
+			},
+			want: []string{"avalue", "fallback", ""},
+		},
 		{
 			name: "Fails parsing unexisting key",
 			args: args{
@@ -124,6 +198,30 @@ func Test_parseDynamicArguments(t *testing.T) {
 			},
 			err: "failed to obtain value of key unexisting key",
 		},
+		{
+			name: "Joins the values of several keys",
+			args: args{
+				dynamicArgs: []string{"concat:sep=/:region,akey", "concat:akey,region"},
+				storage:     safemap,
+			},
+			want: []string{"us-east-1/avalue", "avalueus-east-1"},
+		},
+		{
+			name: "Fails joining an unexisting key",
+			args: args{
+				dynamicArgs: []string{"concat:sep=-:region,unexisting"},
+				storage:     safemap,
+			},
+			err: "failed to obtain value of key unexisting",
+		},
+		{
+			name: "Fails joining without keys after the separator",
+			args: args{
+				dynamicArgs: []string{"concat:sep=-"},
+				storage:     safemap,
+			},
+			err: "concat separator of \"concat:sep=-\" must be followed by \":\" and the keys",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -138,3 +236,589 @@ func Test_parseDynamicArguments(t *testing.T) {
 		})
 	}
 }
+
+func Test_runCommand(t *testing.T) {
+	type args struct {
+		bin         string
+		dir         string
+		args        []string
+		interactive []string
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantStdout string
+		wantStderr string
+		wantErr    bool
+	}{
+		{
+			name:       "Captures the standard output",
+			args:       args{bin: mockBin, args: enginetest.MockCommand{Stdout: "some output\n"}.Args()},
+			wantStdout: "some output\n",
+		},
+		{
+			name:       "Writes the interactive lines to the standard input",
+			args:       args{bin: mockBin, args: enginetest.MockCommand{EchoStdin: true}.Args(), interactive: []string{"first", "second"}},
+			wantStdout: "first\nsecond\n",
+		},
+		{
+			name:       "Runs the command in the working directory",
+			args:       args{bin: mockBin, args: enginetest.MockCommand{PrintWorkingDir: true}.Args(), dir: "/"},
+			wantStdout: "/\n",
+		},
+		{
+			name:       "Captures the standard error",
+			args:       args{bin: mockBin, args: enginetest.MockCommand{Stderr: "unexisting_path\n", ExitCode: 2}.Args()},
+			wantStderr: "unexisting_path",
+			wantErr:    true,
+		},
+		{
+			name:    "Returns an error when the binary can't be started",
+			args:    args{bin: "anunexistingbinary"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runCommand(context.Background(), invocation{
+				bin: tt.args.bin, args: tt.args.args, dir: tt.args.dir, interactive: tt.args.interactive,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("runCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("runCommand() stdout = %v, want %v", got, tt.wantStdout)
+			}
+			if got := stderr.String(); !strings.Contains(got, tt.wantStderr) {
+				t.Errorf("runCommand() stderr = %v, want %v", got, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func Test_elideOutputs(t *testing.T) {
+	type args struct {
+		msg     string
+		limit   int
+		outputs []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "Keeps the outputs which are shorter than the limit",
+			args: args{msg: "error: \"short\"", limit: 10, outputs: []string{"short"}},
+			want: "error: \"short\"",
+		},
+		{
+			name: "Elides the outputs which are longer than the limit",
+			args: args{
+				msg:     "didn't find x in \"0123456789abcdef\" or \"012345\"",
+				limit:   6,
+				outputs: []string{"012345", "0123456789abcdef"},
+			},
+			want: "didn't find x in \"012\n[... 10 bytes elided ...]\ndef\" or \"012345\"",
+		},
+		{
+			name: "Doesn't split multi-byte runes",
+			args: args{msg: "ñññññ", limit: 4, outputs: []string{"ñññññ"}},
+			want: "ñ\n[... 6 bytes elided ...]\nñ",
+		},
+		{
+			name: "Doesn't elide when the limit is disabled",
+			args: args{msg: "0123456789", outputs: []string{"0123456789"}},
+			want: "0123456789",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := elideOutputs(tt.args.msg, tt.args.limit, tt.args.outputs...); got != tt.want {
+				t.Errorf("elideOutputs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_exitCode(t *testing.T) {
+	_, notFoundErr := exec.LookPath("anunexistingbinary")
+	hanging := invocation{bin: mockBin, args: enginetest.MockCommand{Delay: 10 * time.Second}.Args()}
+
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, notStartedErr := runCommand(done, hanging)
+
+	expiring, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, killedErr := runCommand(expiring, hanging)
+
+	signaled := exec.Command(hanging.bin, hanging.args...)
+	if err := signaled.Start(); err != nil {
+		t.Fatal(err)
+	}
+	signaled.Process.Kill()
+	signaledErr := signaled.Wait()
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "Returns 0 when there's no error", want: 0},
+		{name: "Returns the exit code", err: exec.Command(mockBin, enginetest.MockCommand{ExitCode: 3}.Args()...).Run(), want: 3},
+		{name: "Returns -1 when the command didn't run", err: notFoundErr, want: -1},
+		{name: "Returns -1 when the command was signaled", err: signaledErr, want: -1},
+		{name: "Returns -1 when the command was killed once the context was done", err: killedErr, want: -1},
+		{name: "Returns -1 when the context was done before the command started", err: notStartedErr, want: -1},
+		{name: "Returns -1 on an unknown error", err: errors.New("unknown"), want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shouldRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []string
+		stderr     string
+		want       bool
+	}{
+		{name: "Retries when there are no conditions", stderr: "some error", want: true},
+		{
+			name:       "Retries when the standard error matches a condition",
+			conditions: []string{"timeout", "connection reset"},
+			stderr:     "read: connection reset by peer",
+			want:       true,
+		},
+		{
+			name:       "Doesn't retry when the standard error doesn't match",
+			conditions: []string{"connection reset"},
+			stderr:     "invalid argument",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRetry(tt.conditions, tt.stderr); got != tt.want {
+				t.Errorf("shouldRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_runPreCommands(t *testing.T) {
+	dir := t.TempDir()
+	// The mock command ignores the arguments after the "--".
+	failing := append([]string{mockBin}, enginetest.MockCommand{ExitCode: 1}.Args()...)
+	failing = append(failing, "--", "--pass", "secret")
+	tests := []struct {
+		name     string
+		commands [][]string
+		err      string
+	}{
+		{
+			name:     "Runs the commands in the working directory",
+			commands: [][]string{{"mkdir", "config"}, {"touch", "config/cli.yml"}},
+		},
+		{
+			name:     "Fails on the first command which errors",
+			commands: [][]string{failing, {"touch", "unreached"}},
+			err:      "-- --pass [REDACTED]\" failed: exit status 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runPreCommands(context.Background(), tt.commands, dir)
+			if tt.err == "" && err != nil {
+				t.Errorf("runPreCommands() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("runPreCommands() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "config", "cli.yml")); err != nil {
+		t.Errorf("runPreCommands() didn't create the file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unreached")); err == nil {
+		t.Error("runPreCommands() ran the commands after the failed one")
+	}
+}
+
+func Test_missingEnv(t *testing.T) {
+	t.Setenv("TESTCLI_TEST_SET", "value")
+	t.Setenv("TESTCLI_TEST_EMPTY", "")
+
+	got := missingEnv([]string{"TESTCLI_TEST_SET", "TESTCLI_TEST_EMPTY", "TESTCLI_TEST_UNSET"})
+	want := []string{"TESTCLI_TEST_EMPTY", "TESTCLI_TEST_UNSET"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("missingEnv() = %v, want %v", got, want)
+	}
+}
+
+func Test_runCommand_cancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err := runCommand(ctx, invocation{bin: mockBin, args: enginetest.MockCommand{Delay: 10 * time.Second}.Args()})
+	if err == nil {
+		t.Error("runCommand() error = nil, want the command to be killed")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("runCommand() took %s, want the command to be killed", elapsed)
+	}
+}
+
+// A process left by the command holds the output pipes open, which must not
+// keep runCommand from returning.
+func Test_runCommand_orphanHoldsOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		m    enginetest.MockCommand
+	}{
+		{
+			name: "Returns once the killed command's output is closed",
+			m:    enginetest.MockCommand{Delay: 10 * time.Second, Orphan: 10 * time.Second},
+		},
+		{
+			name: "Returns after the command exits",
+			m:    enginetest.MockCommand{Stdout: "done\n", Orphan: 10 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, _, err := runCommand(ctx, invocation{bin: mockBin, args: tt.m.Args()})
+			if err == nil {
+				t.Error("runCommand() error = nil, want the pipes to be closed")
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("runCommand() took %s, want it to return after the wait delay", elapsed)
+			}
+		})
+	}
+}
+
+func Test_runCommand_interactiveDelay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	var written int
+	start := time.Now()
+	stdout, _, err := runCommand(context.Background(), invocation{
+		bin:              mockBin,
+		args:             enginetest.MockCommand{EchoStdin: true}.Args(),
+		interactive:      []string{"first", "second", "third"},
+		interactiveDelay: delay,
+		written:          &written,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "first\nsecond\nthird\n"; stdout.String() != want {
+		t.Errorf("runCommand() stdout = %q, want %q", stdout, want)
+	}
+	if written != 3 {
+		t.Errorf("runCommand() written = %d, want 3", written)
+	}
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("runCommand() took %s, want at least %s between the lines", elapsed, 2*delay)
+	}
+
+	// The rest of the lines aren't written once the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, _ = runCommand(ctx, invocation{
+		bin:              mockBin,
+		args:             enginetest.MockCommand{EchoStdin: true}.Args(),
+		interactive:      []string{"first", "second"},
+		interactiveDelay: time.Hour,
+		written:          &written,
+	})
+	if written != 1 {
+		t.Errorf("runCommand() written = %d, want 1", written)
+	}
+}
+
+func TestExecuteTests_interactiveDelayClock(t *testing.T) {
+	clock := &fakeClock{}
+	tests := Tests{{
+		Name:   "waits an hour between the lines",
+		Binary: mockBin,
+		Args: Args{
+			Args:             enginetest.MockCommand{EchoStdin: true}.Args(),
+			Interactive:      []string{"first", "second", "third"},
+			InteractiveDelay: time.Hour,
+		},
+		Assert: Assertions{Must: Assertion{Strict: true, Output: []string{"first\nsecond\nthird\n"}}},
+	}}
+	ExecuteTests(t, tests, WithClock(clock), WithCooldown(0))
+
+	if got := clock.Now().Sub(time.Time{}); got != 2*time.Hour {
+		t.Errorf("ExecuteTests() waited %s on the clock, want %s", got, 2*time.Hour)
+	}
+}
+
+func TestExecuteTestsContext_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The failing test is skipped since the context is done.
+	tests := Tests{{Name: "never started", Binary: mockBin, Args: mockArgs(enginetest.MockCommand{ExitCode: 1})}}
+	ExecuteTestsContext(ctx, t, tests)
+}
+
+func Test_runCommand_combined(t *testing.T) {
+	var combined syncBuffer
+	stdout, stderr, err := runCommand(context.Background(), invocation{
+		bin:      mockBin,
+		args:     enginetest.MockCommand{Stdout: "out\n", Stderr: "err\n", Pause: 100 * time.Millisecond}.Args(),
+		combined: &combined,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("runCommand() stdout = %q, stderr = %q", stdout, stderr)
+	}
+	if want := "out\nerr\n"; combined.String() != want {
+		t.Errorf("runCommand() combined = %q, want %q", combined.String(), want)
+	}
+}
+
+// Writing to both streams at once must not race on the writers they share,
+// which is caught when the tests are run with the race detector.
+func Test_runCommand_sharedWriters(t *testing.T) {
+	var combined, tee bytes.Buffer
+	stdout, stderr, err := runCommand(context.Background(), invocation{
+		bin:       mockBin,
+		args:      enginetest.MockCommand{Stdout: "out\n", Stderr: "err\n", Repeat: 200}.Args(),
+		combined:  &combined,
+		stdoutTee: &tee,
+		stderrTee: &tee,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := stdout.Len() + stderr.Len()
+	if combined.Len() != want {
+		t.Errorf("runCommand() combined has %d bytes, want %d", combined.Len(), want)
+	}
+	if tee.Len() != want {
+		t.Errorf("runCommand() tee has %d bytes, want %d", tee.Len(), want)
+	}
+	if got := len(splitLines(tee.String())); got != 400 {
+		t.Errorf("runCommand() tee has %d lines, want 400", got)
+	}
+}
+
+func TestExecuteTests_namespacedStorage(t *testing.T) {
+	tests := Tests{
+		{
+			Name:           "store id",
+			Binary:         mockBin,
+			Args:           mockArgs(enginetest.MockCommand{Stdout: "1"}),
+			StoreStdoutKey: "namespaced_id",
+		},
+		{
+			Name:           "store global id",
+			Binary:         mockBin,
+			Args:           mockArgs(enginetest.MockCommand{Stdout: "2"}),
+			StoreStdoutKey: teststorage.GlobalKeyPrefix + "namespaced_id",
+		},
+		{
+			Name:   "read global id",
+			Binary: mockBin,
+			Args: Args{
+				Args:        []string{"-stdout"},
+				DynamicArgs: []string{teststorage.GlobalKeyPrefix + "namespaced_id"},
+			},
+			Assert: Assertions{Must: Assertion{Strict: true, Output: []string{"2"}}},
+		},
+	}
+	ExecuteTests(t, tests, WithNamespacedStorage(), WithCooldown(0))
+
+	storage := teststorage.GetInMemory()
+	if got, _ := storage.Get("store_id/namespaced_id"); got != "1" {
+		t.Errorf("namespaced key = %v, want 1", got)
+	}
+	if _, found := storage.Get("namespaced_id"); found {
+		t.Error("key was stored without its namespace")
+	}
+}
+
+func Test_commandEnv(t *testing.T) {
+	environ := []string{"HOME=/home/user", "TOKEN=secret", "LANG=en_US.UTF-8"}
+	tests := []struct {
+		name      string
+		unset     []string
+		overrides map[string]string
+		want      []string
+	}{
+		{
+			name: "inherits the environment",
+			want: environ,
+		},
+		{
+			name:  "removes the unset variables",
+			unset: []string{"TOKEN", "MISSING"},
+			want:  []string{"HOME=/home/user", "LANG=en_US.UTF-8"},
+		},
+		{
+			name:      "sets the overrides",
+			overrides: map[string]string{"LANG": "C", "DEBUG": "1"},
+			want:      []string{"HOME=/home/user", "TOKEN=secret", "DEBUG=1", "LANG=C"},
+		},
+		{
+			name:      "keeps the overrides which are also unset",
+			unset:     []string{"TOKEN"},
+			overrides: map[string]string{"TOKEN": "fake"},
+			want:      []string{"HOME=/home/user", "LANG=en_US.UTF-8", "TOKEN=fake"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandEnv(environ, tt.unset, tt.overrides); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commandEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteTests_env(t *testing.T) {
+	t.Setenv("TESTCLI_TEST_TOKEN", "secret")
+	tests := Tests{
+		{
+			Name:     "unsets and overrides the environment",
+			Binary:   mockBin,
+			Args:     mockArgs(enginetest.MockCommand{PrintEnv: []string{"TESTCLI_TEST_TOKEN", "TESTCLI_TEST_MODE"}}),
+			UnsetEnv: []string{"TESTCLI_TEST_TOKEN"},
+			Env:      map[string]string{"TESTCLI_TEST_MODE": "offline"},
+			Assert: Assertions{
+				Must: Assertion{Strict: true, Output: []string{"TESTCLI_TEST_TOKEN=\nTESTCLI_TEST_MODE=offline\n"}},
+			},
+		},
+	}
+	ExecuteTests(t, tests, WithCooldown(0))
+}
+
+func Test_runCommand_onOutput(t *testing.T) {
+	var lines []string
+	var firstLine time.Time
+	start := time.Now()
+	_, _, err := runCommand(context.Background(), invocation{
+		bin: mockBin,
+		// The standard output is printed at once, but the command exits after
+		// the pause.
+		args: enginetest.MockCommand{Stdout: "started\r\nprogress 50%\ndone", Pause: 500 * time.Millisecond}.Args(),
+		onOutput: func(line string) {
+			if len(lines) == 0 {
+				firstLine = time.Now()
+			}
+			lines = append(lines, line)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"started", "progress 50%", "done"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("runCommand() lines = %q, want %q", lines, want)
+	}
+	if elapsed := firstLine.Sub(start); elapsed >= 500*time.Millisecond {
+		t.Errorf("runCommand() first line after %s, want it before the command finished", elapsed)
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("closed") }
+
+func Test_runCommand_tee(t *testing.T) {
+	var tee syncBuffer
+	stdout, stderr, err := runCommand(context.Background(), invocation{
+		bin:       mockBin,
+		args:      enginetest.MockCommand{Stdout: "out\n", Stderr: "err\n"}.Args(),
+		stdoutTee: &tee,
+		stderrTee: failingWriter{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tee.String() != "out\n" {
+		t.Errorf("runCommand() tee = %q, want %q", tee.String(), "out\n")
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("runCommand() stdout = %q, stderr = %q", stdout, stderr)
+	}
+}
+
+func TestExecuteTests_outputWriter(t *testing.T) {
+	var out, errOut bytes.Buffer
+	tests := Tests{
+		{
+			Name:         "copies the output",
+			Binary:       mockBin,
+			Args:         mockArgs(enginetest.MockCommand{Stdout: "out\n", Stderr: "err\n"}),
+			OutputWriter: &out,
+			ErrorWriter:  &errOut,
+			Assert: Assertions{
+				Must: Assertion{Strict: true, Output: []string{"out\n"}, Errors: []string{"err"}},
+			},
+		},
+	}
+	ExecuteTests(t, tests, WithCooldown(0))
+
+	if out.String() != "out\n" || errOut.String() != "err\n" {
+		t.Errorf("OutputWriter = %q, ErrorWriter = %q", out.String(), errOut.String())
+	}
+}
+
+func Test_runCommand_written(t *testing.T) {
+	// The lines exceed the pipe buffer, so they can't all be written once the
+	// command exits without reading them.
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = strings.Repeat("y", 4096)
+	}
+
+	tests := []struct {
+		name string
+		m    enginetest.MockCommand
+		want func(written int) bool
+	}{
+		{
+			name: "writes all the lines which are read",
+			m:    enginetest.MockCommand{EchoStdin: true},
+			want: func(written int) bool { return written == len(lines) },
+		},
+		{
+			name: "stops writing once the command exits",
+			want: func(written int) bool { return written < len(lines) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var written int
+			_, _, err := runCommand(context.Background(), invocation{
+				bin: mockBin, args: tt.m.Args(), interactive: lines, written: &written,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.want(written) {
+				t.Errorf("runCommand() written = %d of %d lines", written, len(lines))
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+// ExitCodeKeyPrefix is the prefix of the storage keys where the exit code of
+// each test's command is recorded, see ExitCodeKey. The keys are global, so
+// they're shared by namespaced storages.
+const ExitCodeKeyPrefix = teststorage.GlobalKeyPrefix + "testcli.exit_code."
+
+// ExitCodeKey returns the storage key where the exit code of the last attempt
+// of the test with the name is recorded. It's only recorded when the test
+// runs its command.
+func ExitCodeKey(name string) string { return ExitCodeKeyPrefix + name }
+
+// StoredExitCode returns the exit code recorded for the test with the name,
+// failing when the test hasn't run its command.
+func StoredExitCode(storage teststorage.Storage, name string) (int, error) {
+	value, ok := storage.Get(ExitCodeKey(name))
+	if !ok {
+		return 0, fmt.Errorf("test \"%s\" hasn't run its command", name)
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("exit code \"%s\" of test \"%s\" is invalid: %w", value, name, err)
+	}
+	return code, nil
+}
+
+// ExitCodeCondition ties the exit code of a test to the exit code of a test
+// which ran before it, such as a cleanup command which must succeed only when
+// the provisioning command failed. The test should DependsOn the other test
+// so it runs after it.
+type ExitCodeCondition struct {
+	// Name of the test whose exit code is checked.
+	Test string
+
+	// Range of the other test's exit code which triggers the condition.
+	When ExitCodeRange
+
+	// Range which the test's exit code must be within when the condition is
+	// triggered.
+	Then ExitCodeRange
+}
+
+func assertExitCodeConditions(conditions []ExitCodeCondition, code int, storage teststorage.Storage) error {
+	var errs []error
+	for _, c := range conditions {
+		other, err := StoredExitCode(storage, c.Test)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if other < c.When.Min || other > c.When.Max {
+			continue
+		}
+		if code < c.Then.Min || code > c.Then.Max {
+			errs = append(errs, fmt.Errorf(
+				"exit code %d is not within [%d, %d] when test \"%s\" exited with %d",
+				code, c.Then.Min, c.Then.Max, c.Test, other,
+			))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must meet exit code conditions", errors.Join(errs...))
+	}
+	return nil
+}
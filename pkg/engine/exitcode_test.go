@@ -0,0 +1,112 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func Test_assertExitCodeConditions(t *testing.T) {
+	storage := teststorage.NewSafeMap()
+	storage.Set(ExitCodeKey("provision failed"), "1")
+	storage.Set(ExitCodeKey("provision passed"), "0")
+	storage.Set(ExitCodeKey("invalid"), "none")
+
+	// The cleanup must succeed when the provisioning failed.
+	cleanup := func(test string) ExitCodeCondition {
+		return ExitCodeCondition{Test: test, When: ExitCodeRange{Min: 1, Max: 255}, Then: ExitCodeRange{}}
+	}
+	tests := []struct {
+		name       string
+		conditions []ExitCodeCondition
+		code       int
+		err        string
+	}{
+		{name: "no conditions", code: 3},
+		{
+			name:       "passes when the condition is met",
+			conditions: []ExitCodeCondition{cleanup("provision failed")},
+		},
+		{
+			name:       "passes when the condition isn't triggered",
+			conditions: []ExitCodeCondition{cleanup("provision passed")},
+			code:       2,
+		},
+		{
+			name:       "fails when the condition isn't met",
+			conditions: []ExitCodeCondition{cleanup("provision failed")},
+			code:       2,
+			err:        "exit code 2 is not within [0, 0] when test \"provision failed\" exited with 1",
+		},
+		{
+			name:       "fails when the other test hasn't run",
+			conditions: []ExitCodeCondition{cleanup("provision skipped")},
+			err:        "test \"provision skipped\" hasn't run its command",
+		},
+		{
+			name:       "fails when the stored exit code is invalid",
+			conditions: []ExitCodeCondition{cleanup("invalid")},
+			err:        "exit code \"none\" of test \"invalid\" is invalid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertExitCodeConditions(tt.conditions, tt.code, storage)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertExitCodeConditions() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertExitCodeConditions() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestExecuteTests_storesExitCode(t *testing.T) {
+	tests := Tests{
+		{
+			Name:   "exit code provision",
+			Binary: mockBin,
+			Args:   mockArgs(enginetest.MockCommand{ExitCode: 1}),
+			Assert: Assertions{WantErr: true},
+		},
+		{
+			Name:      "exit code cleanup",
+			Binary:    mockBin,
+			Args:      mockArgs(enginetest.MockCommand{}),
+			DependsOn: []string{"exit code provision"},
+			Assert: Assertions{
+				CanError: true,
+				ExitCodeConditions: []ExitCodeCondition{{
+					Test: "exit code provision",
+					When: ExitCodeRange{Min: 1, Max: 255},
+					Then: ExitCodeRange{Min: 0, Max: 0},
+				}},
+			},
+		},
+	}
+	ExecuteTests(t, tests, WithCooldown(0), WithNamespacedStorage())
+
+	if code, err := StoredExitCode(teststorage.GetInMemory(), "exit code cleanup"); err != nil || code != 0 {
+		t.Errorf("StoredExitCode() = %d, %v, want 0", code, err)
+	}
+}
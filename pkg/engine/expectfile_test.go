@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_readExpectedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expected.txt")
+	contents := "# Columns of the table\nNAME\r\n\n   \n  # indented comment\n  cluster started\nregion: us-east-1\n"
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readExpectedFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"NAME", "  cluster started", "region: us-east-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("readExpectedFile() = %q, want %q", got, want)
+	}
+}
+
+func Test_assertMust_mustContainFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expected.txt")
+	if err := os.WriteFile(path, []byte("# Expected values\ncluster\nstarted\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		out  string
+		file string
+		err  string
+	}{
+		{
+			name: "Passes when all the values are found",
+			out:  "cluster started\n",
+			file: path,
+		},
+		{
+			name: "Fails when a value is missing",
+			out:  "cluster stopped\n",
+			file: path,
+			err:  "didn't find \"started\" in standard output",
+		},
+		{
+			name: "Fails when the file doesn't exist",
+			file: filepath.Join(filepath.Dir(path), "missing.txt"),
+			err:  "failed reading the expected values file: open " + filepath.Join(filepath.Dir(path), "missing.txt"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertMust(tt.out, "", Assertion{MustContainFile: tt.file}, nil, time.Now)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertMust() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertMust() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// failureCache remembers the names of the tests which failed on the last run
+// in a file, so the next run can be limited to them.
+type failureCache struct {
+	path string
+
+	// Names of the tests to run, nil when all of them run.
+	only map[string]bool
+
+	mu     sync.Mutex
+	failed map[string]bool
+}
+
+// newFailureCache reads the failure cache found in the path. When failedOnly
+// is set, only the cached tests and the tests they depend on are run. A
+// missing cache runs all the tests.
+func newFailureCache(path string, failedOnly bool, tests Tests) (*failureCache, error) {
+	c := failureCache{path: path, failed: make(map[string]bool)}
+	if !failedOnly {
+		return &c, nil
+	}
+
+	names, err := readFailureCache(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]Test, len(tests))
+	for _, tt := range tests {
+		index[tt.Name] = tt
+	}
+
+	// The tests which the failed tests depend on must run too, otherwise the
+	// failed tests would be skipped.
+	c.only = make(map[string]bool)
+	for len(names) > 0 {
+		name := names[0]
+		names = names[1:]
+		if c.only[name] {
+			continue
+		}
+		c.only[name] = true
+		names = append(names, index[name].DependsOn...)
+	}
+	return &c, nil
+}
+
+func readFailureCache(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// skip returns true when the test must not run since it didn't fail on the
+// last run.
+func (c *failureCache) skip(name string) bool {
+	if c == nil || c.only == nil {
+		return false
+	}
+	return !c.only[name]
+}
+
+// record marks whether the test failed.
+func (c *failureCache) record(name string, failed bool) {
+	if c == nil || !failed {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failed[name] = true
+}
+
+// save writes the names of the failed tests to the cache, one per line.
+func (c *failureCache) save() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	names := make([]string, 0, len(c.failed))
+	for name := range c.failed {
+		names = append(names, name)
+	}
+	c.mu.Unlock()
+
+	sort.Strings(names)
+	var contents string
+	if len(names) > 0 {
+		contents = strings.Join(names, "\n") + "\n"
+	}
+	return os.WriteFile(c.path, []byte(contents), 0o600)
+}
@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_failureCache(t *testing.T) {
+	tests := Tests{
+		{Name: "login"},
+		{Name: "create", DependsOn: []string{"login"}},
+		{Name: "delete", DependsOn: []string{"create"}},
+		{Name: "list"},
+	}
+	path := filepath.Join(t.TempDir(), "failures")
+
+	// A missing cache runs all the tests.
+	c, err := newFailureCache(path, true, tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if c.skip(tt.Name) {
+			t.Errorf("failureCache.skip(%s) = true, want false without a cache", tt.Name)
+		}
+	}
+
+	c.record("list", false)
+	c.record("delete", true)
+	c.record("create", true)
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(contents), "create\ndelete\n"; got != want {
+		t.Errorf("failureCache.save() = %q, want %q", got, want)
+	}
+
+	// The failed tests run again along with the tests they depend on.
+	c, err = newFailureCache(path, true, tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"login": false, "create": false, "delete": false, "list": true}
+	for name, skip := range want {
+		if got := c.skip(name); got != skip {
+			t.Errorf("failureCache.skip(%s) = %v, want %v", name, got, skip)
+		}
+	}
+
+	// All the tests run when only the failures are cached.
+	c, err = newFailureCache(path, false, tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.skip("list") {
+		t.Error("failureCache.skip(list) = true, want false when all the tests run")
+	}
+}
@@ -0,0 +1,93 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileAssertion defines a file which the command must have created or must not
+// have created.
+type FileAssertion struct {
+	// Path of the file, relative to the test's WorkingDir when it's not
+	// absolute.
+	Path string
+
+	// When set, the file must exist. When unset and ContentContains is empty,
+	// the file must not exist.
+	MustExist bool
+
+	// Values which must be found in the file contents. The file must exist
+	// when any are set.
+	ContentContains []string
+}
+
+func assertFiles(dir string, assertions []FileAssertion) error {
+	var errs []error
+	for _, f := range assertions {
+		if err := assertFile(dir, f); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("file assertions", errors.Join(errs...))
+	}
+	return nil
+}
+
+func assertFile(dir string, f FileAssertion) error {
+	path := f.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	mustExist := f.MustExist || len(f.ContentContains) > 0
+	_, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && !mustExist:
+		return nil
+	case errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("file \"%s\" doesn't exist", path)
+	case err != nil:
+		return fmt.Errorf("failed to check file \"%s\": %w", path, err)
+	case !mustExist:
+		return fmt.Errorf("file \"%s\" must not exist", path)
+	case len(f.ContentContains) == 0:
+		return nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file \"%s\": %w", path, err)
+	}
+
+	var errs []error
+	for _, want := range f.ContentContains {
+		if !strings.Contains(string(contents), want) {
+			errs = append(errs, fmt.Errorf(
+				"didn't find \"%s\" in file \"%s\": \"%s\"", want, path, contents,
+			))
+		}
+	}
+	return errors.Join(errs...)
+}
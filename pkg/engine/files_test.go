@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_assertFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte("region: us-east-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		f    FileAssertion
+		err  string
+	}{
+		{
+			name: "Passes when the file exists",
+			f:    FileAssertion{Path: "config.yml", MustExist: true},
+		},
+		{
+			name: "Passes when the file contains the values",
+			f:    FileAssertion{Path: filepath.Join(dir, "config.yml"), ContentContains: []string{"region", "us-east-1"}},
+		},
+		{
+			name: "Passes when the file doesn't exist and must not",
+			f:    FileAssertion{Path: "unexisting.yml"},
+		},
+		{
+			name: "Fails when the file doesn't exist",
+			f:    FileAssertion{Path: "unexisting.yml", ContentContains: []string{"region"}},
+			err:  "unexisting.yml\" doesn't exist",
+		},
+		{
+			name: "Fails when the file exists and must not",
+			f:    FileAssertion{Path: "config.yml"},
+			err:  "config.yml\" must not exist",
+		},
+		{
+			name: "Fails when the file doesn't contain the value",
+			f:    FileAssertion{Path: "config.yml", ContentContains: []string{"eu-west-1"}},
+			err:  "didn't find \"eu-west-1\" in file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertFiles(dir, []FileAssertion{tt.f})
+			if tt.err == "" && err != nil {
+				t.Errorf("assertFiles() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertFiles() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
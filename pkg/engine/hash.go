@@ -0,0 +1,44 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+func assertSHA256(out string, w Assertion) error {
+	if w.OutputSHA256 == "" {
+		return nil
+	}
+
+	normalized, err := normalize(out, w.OutputSHA256Normalize)
+	if err != nil {
+		return NewPrefixedError("must match SHA-256", err)
+	}
+
+	sum := sha256.Sum256([]byte(normalized))
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, w.OutputSHA256) {
+		return NewPrefixedError("must match SHA-256",
+			fmt.Errorf("standard output digest is %s, want %s", got, w.OutputSHA256),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_assertSHA256(t *testing.T) {
+	// The digest of "cluster healthy\n".
+	const digest = "e3cd9a9d2d125abcafe627a4ad82392d537de21e3747d9eb4a42241e5cc40aa0"
+	tests := []struct {
+		name string
+		out  string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "no digest",
+			out:  "cluster healthy\n",
+		},
+		{
+			name: "matching digest",
+			out:  "cluster healthy\n",
+			w:    Assertion{OutputSHA256: digest},
+		},
+		{
+			name: "matching uppercase digest",
+			out:  "cluster healthy\n",
+			w:    Assertion{OutputSHA256: strings.ToUpper(digest)},
+		},
+		{
+			name: "matching digest after normalizing",
+			out:  "cluster unhealthy\n",
+			w: Assertion{
+				OutputSHA256:          digest,
+				OutputSHA256Normalize: []Replacement{{Pattern: "unhealthy", Replace: "healthy"}},
+			},
+		},
+		{
+			name: "reports both digests",
+			out:  "cluster unhealthy\n",
+			w:    Assertion{OutputSHA256: digest},
+			err:  "must match SHA-256\nstandard output digest is f6bc4bbcadcecfed76fabc86b990d9db76d2a9e70b62ccda31032a890eb834ef, want e3cd9a9d2d125abcafe627a4ad82392d537de21e3747d9eb4a42241e5cc40aa0",
+		},
+		{
+			name: "invalid normalize pattern",
+			out:  "cluster healthy\n",
+			w:    Assertion{OutputSHA256: digest, OutputSHA256Normalize: []Replacement{{Pattern: "("}}},
+			err:  "normalize pattern \"(\" did not compile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertSHA256(tt.out, tt.w)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("assertSHA256() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("assertSHA256() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// httpStatusPattern matches an HTTP status code followed by its reason phrase,
+// such as "200 OK" or "HTTP/1.1 404 Not Found", capturing the code.
+var httpStatusPattern = regexp.MustCompile(`\b([1-5]\d{2}) [A-Z][A-Za-z'-]*(?: [A-Z][A-Za-z'-]*)*\b`)
+
+// findHTTPStatus returns the first HTTP status found in the standard output,
+// or in the standard error when there's none, along with the matched line.
+func findHTTPStatus(stdout, stderr string) (code int, match string, stream Stream, found bool) {
+	for _, s := range []struct {
+		out    string
+		stream Stream
+	}{{stdout, Stdout}, {stderr, Stderr}} {
+		if m := httpStatusPattern.FindStringSubmatch(s.out); m != nil {
+			code, _ = strconv.Atoi(m[1])
+			return code, m[0], s.stream, true
+		}
+	}
+	return 0, "", "", false
+}
+
+func assertHTTPStatus(stdout, stderr string, want *int) error {
+	if want == nil {
+		return nil
+	}
+
+	code, match, stream, found := findHTTPStatus(stdout, stderr)
+	if !found {
+		return NewPrefixedError("must have HTTP status", fmt.Errorf(
+			"no HTTP status found in the standard output or error, want %d", *want,
+		))
+	}
+	if code != *want {
+		return NewPrefixedError("must have HTTP status", fmt.Errorf(
+			"found status %d (\"%s\") in %s, want %d", code, match, stream, *want,
+		))
+	}
+	return nil
+}
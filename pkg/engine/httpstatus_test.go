@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_assertHTTPStatus(t *testing.T) {
+	ok, notFound := 200, 404
+	tests := []struct {
+		name           string
+		stdout, stderr string
+		want           *int
+		err            string
+	}{
+		{
+			name:   "Ignores the status when unset",
+			stdout: "404 Not Found\n",
+		},
+		{
+			name:   "Passes when the status is equal",
+			stdout: "GET /api/v1/clusters\n200 OK\n",
+			want:   &ok,
+		},
+		{
+			name:   "Finds the status of a status line",
+			stdout: "HTTP/1.1 404 Not Found\nContent-Type: application/json\n",
+			want:   &notFound,
+		},
+		{
+			name:   "Finds the status in the standard error",
+			stdout: "{}\n",
+			stderr: "request failed: 404 Not Found\n",
+			want:   &notFound,
+		},
+		{
+			name:   "Fails showing the found and expected status",
+			stdout: "took 350 ms\nHTTP/1.1 404 Not Found\n",
+			want:   &ok,
+			err:    "found status 404 (\"404 Not Found\") in standard output, want 200",
+		},
+		{
+			name:   "Fails when there's no status",
+			stdout: "took 350 ms\n",
+			want:   &ok,
+			err:    "no HTTP status found in the standard output or error, want 200",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertHTTPStatus(tt.stdout, tt.stderr, tt.want)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertHTTPStatus() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertHTTPStatus() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Replacement replaces the matches of a regex pattern in the output, such as
+// masking the timestamps with a fixed value.
+type Replacement struct {
+	// Regex pattern to replace.
+	Pattern string
+
+	// Value which replaces the matches, which can reference the pattern
+	// groups as in regexp.Regexp.ReplaceAllString.
+	Replace string
+}
+
+// normalize applies the replacements to out in order.
+func normalize(out string, replacements []Replacement) (string, error) {
+	for _, r := range replacements {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return "", fmt.Errorf("normalize pattern \"%s\" did not compile: %w", r.Pattern, err)
+		}
+		out = re.ReplaceAllString(out, r.Replace)
+	}
+	return out, nil
+}
+
+// assertIdempotent ensures that the standard output of two consecutive runs is
+// identical once normalized, reporting the first line which differs.
+func assertIdempotent(first, second string, replacements []Replacement) error {
+	if err := compareRuns(first, second, "second run", replacements); err != nil {
+		return NewPrefixedError("must be idempotent", err)
+	}
+	return nil
+}
+
+// compareRuns ensures that the standard output of the named run is identical
+// to the first one once normalized, reporting the first line which differs.
+func compareRuns(first, second, run string, replacements []Replacement) error {
+	first, err := normalize(first, replacements)
+	if err != nil {
+		return err
+	}
+	second, err = normalize(second, replacements)
+	if err != nil {
+		return err
+	}
+	if first == second {
+		return nil
+	}
+
+	// An empty first output has no lines, which must still be compared.
+	want := splitLines(first)
+	if want == nil {
+		want = []string{}
+	}
+	if err := assertOrderedLines(second, want); err != nil {
+		return fmt.Errorf("%s differs: %w", run, err)
+	}
+
+	// The lines are equal, so the outputs only differ in the trailing newline.
+	return fmt.Errorf(
+		"%s differs in the trailing newline, got \"%s\" want \"%s\"", run, second, first,
+	)
+}
@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_assertIdempotent(t *testing.T) {
+	timestamps := []Replacement{{Pattern: `\d{2}:\d{2}:\d{2}`, Replace: "<time>"}}
+	tests := []struct {
+		name          string
+		first, second string
+		normalize     []Replacement
+		err           string
+	}{
+		{
+			name:   "Passes when the outputs are identical",
+			first:  "a\nb\n",
+			second: "a\nb\n",
+		},
+		{
+			name:      "Passes when the outputs are identical once normalized",
+			first:     "created at 10:00:01\nid: a1\n",
+			second:    "created at 10:00:02\nid: a1\n",
+			normalize: timestamps,
+		},
+		{
+			name:      "Reports the first line which differs",
+			first:     "created at 10:00:01\nb\na\n",
+			second:    "created at 10:00:02\na\nb\n",
+			normalize: timestamps,
+			err:       "second run differs: must match lines\nline 1 got \"a\" want \"b\"",
+		},
+		{
+			name:   "Reports the lines of the second run when the first one is empty",
+			second: "a\n",
+			err:    "second run differs: must match lines\nline 0 \"a\" is unexpected, want 0 lines",
+		},
+		{
+			name:   "Fails when only the trailing newline differs",
+			first:  "a\n",
+			second: "a",
+			err:    "second run differs in the trailing newline",
+		},
+		{
+			name:      "Fails when the pattern doesn't compile",
+			first:     "a\n",
+			normalize: []Replacement{{Pattern: `(`}},
+			err:       "normalize pattern \"(\" did not compile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertIdempotent(tt.first, tt.second, tt.normalize)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertIdempotent() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertIdempotent() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package jsonpath resolves simple paths on decoded JSON documents. A path is
+// a list of object keys separated by dots, where array elements are selected
+// with [index] and keys containing dots or brackets are quoted as ["key"].
+// The root of the document is selected with an empty path or "$".
+// e.g. "hrefs", "items[0].id", "hrefs[\"api/v0.1\"]".
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// step is a single element of a path.
+type step struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (s step) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	return strconv.Quote(s.key)
+}
+
+// parse splits the path into steps.
+func parse(path string) ([]step, error) {
+	path = strings.TrimPrefix(path, "$")
+	var steps []step
+	for i := 0; i < len(path); {
+		switch c := path[i]; {
+		case c == '.':
+			i++
+		case c == '[' && strings.HasPrefix(path[i:], `["`):
+			end := strings.Index(path[i+2:], `"]`)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated key in path \"%s\"", path)
+			}
+			steps = append(steps, step{key: path[i+2 : i+2+end]})
+			i += end + 4
+		case c == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated index in path \"%s\"", path)
+			}
+			index, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index \"%s\" in path \"%s\"", path[i+1:i+end], path)
+			}
+			steps = append(steps, step{index: index, isIndex: true})
+			i += end + 1
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			steps = append(steps, step{key: path[i : i+end]})
+			i += end
+		}
+	}
+	return steps, nil
+}
+
+// Get returns the value found in the path of a document decoded with
+// encoding/json into an interface{}.
+func Get(doc interface{}, path string) (interface{}, error) {
+	steps, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := doc
+	for i, s := range steps {
+		next, err := s.get(current)
+		if err != nil {
+			return nil, fmt.Errorf("path \"%s\" not found: %s at %s", path, err, format(steps[:i+1]))
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// Set replaces the value found in the path of a document decoded with
+// encoding/json into an interface{}, returning the updated document. The
+// parent of the value must exist, the value itself is added when it's an
+// object key which doesn't exist.
+func Set(doc interface{}, path string, value interface{}) (interface{}, error) {
+	steps, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return value, nil
+	}
+
+	parent := doc
+	for i, s := range steps[:len(steps)-1] {
+		next, err := s.get(parent)
+		if err != nil {
+			return nil, fmt.Errorf("path \"%s\" not found: %s at %s", path, err, format(steps[:i+1]))
+		}
+		parent = next
+	}
+
+	last := steps[len(steps)-1]
+	if err := last.set(parent, value); err != nil {
+		return nil, fmt.Errorf("path \"%s\" can't be set: %s at %s", path, err, format(steps))
+	}
+	return doc, nil
+}
+
+func (s step) set(parent, value interface{}) error {
+	if s.isIndex {
+		array, ok := parent.([]interface{})
+		if !ok {
+			return fmt.Errorf("value is not an array")
+		}
+		if s.index >= len(array) {
+			return fmt.Errorf("index out of range (length %d)", len(array))
+		}
+		array[s.index] = value
+		return nil
+	}
+
+	object, ok := parent.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("value is not an object")
+	}
+	object[s.key] = value
+	return nil
+}
+
+func (s step) get(value interface{}) (interface{}, error) {
+	if s.isIndex {
+		array, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("value is not an array")
+		}
+		if s.index >= len(array) {
+			return nil, fmt.Errorf("index out of range (length %d)", len(array))
+		}
+		return array[s.index], nil
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("value is not an object")
+	}
+	v, ok := object[s.key]
+	if !ok {
+		return nil, fmt.Errorf("key doesn't exist")
+	}
+	return v, nil
+}
+
+func format(steps []step) string {
+	var parts []string
+	for _, s := range steps {
+		parts = append(parts, s.String())
+	}
+	return strings.Join(parts, "")
+}
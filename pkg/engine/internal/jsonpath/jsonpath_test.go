@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{
+		"message": "You Know, for Cloud.",
+		"hrefs": {"api/v0.1": "https://api.elastic-cloud.com/api/v0.1"},
+		"items": [{"id": "a"}, {"id": "b", "tags": ["x", "y"]}]
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want interface{}
+		err  string
+	}{
+		{name: "Obtains the root", path: "$", want: doc},
+		{name: "Obtains a key", path: "message", want: "You Know, for Cloud."},
+		{name: "Obtains a quoted key", path: `hrefs["api/v0.1"]`, want: "https://api.elastic-cloud.com/api/v0.1"},
+		{name: "Obtains an array element", path: "$.items[1].id", want: "b"},
+		{name: "Obtains a nested array element", path: "items[1].tags[0]", want: "x"},
+		{
+			name: "Fails on unexisting keys",
+			path: "items[0].name",
+			err:  "path \"items[0].name\" not found: key doesn't exist at \"items\"[0]\"name\"",
+		},
+		{
+			name: "Fails on out of range indexes",
+			path: "items[2]",
+			err:  "index out of range (length 2)",
+		},
+		{
+			name: "Fails when indexing an object",
+			path: "hrefs[0]",
+			err:  "value is not an array",
+		},
+		{
+			name: "Fails on invalid indexes",
+			path: "items[a]",
+			err:  "invalid index \"a\" in path \"items[a]\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Get(doc, tt.path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Get() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	const doc = `{"id": "a1", "items": [{"id": "b"}, {"id": "c"}]}`
+	tests := []struct {
+		name string
+		path string
+		want string
+		err  string
+	}{
+		{name: "Replaces a key", path: "id", want: `{"id": "*", "items": [{"id": "b"}, {"id": "c"}]}`},
+		{name: "Replaces an array element", path: "items[1]", want: `{"id": "a1", "items": [{"id": "b"}, "*"]}`},
+		{name: "Adds an unexisting key", path: "items[0].name", want: `{"id": "a1", "items": [{"id": "b", "name": "*"}, {"id": "c"}]}`},
+		{name: "Replaces the root", path: "$", want: `"*"`},
+		{
+			name: "Fails when the parent doesn't exist",
+			path: "meta.id",
+			err:  "path \"meta.id\" not found: key doesn't exist at \"meta\"",
+		},
+		{
+			name: "Fails on out of range indexes",
+			path: "items[2]",
+			err:  "path \"items[2]\" can't be set: index out of range (length 2)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got interface{}
+			if err := json.Unmarshal([]byte(doc), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Set(got, tt.path, "*")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Set() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Set() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/elastic/testcli/pkg/engine/internal/jsonpath"
+)
+
+func assertJSONArrayLen(out string, lengths map[string]int) error {
+	if len(lengths) == 0 {
+		return nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		return NewPrefixedError("must match JSON array lengths",
+			fmt.Errorf("standard output is not valid JSON: %w: \"%s\"", err, out),
+		)
+	}
+
+	paths := make([]string, 0, len(lengths))
+	for path := range lengths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var errs []error
+	for _, path := range paths {
+		value, err := jsonpath.Get(doc, path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		array, ok := value.([]interface{})
+		if !ok {
+			errs = append(errs, fmt.Errorf("value in path \"%s\" is not an array", path))
+			continue
+		}
+		if want := lengths[path]; len(array) != want {
+			errs = append(errs, fmt.Errorf("array in path \"%s\" has %d elements, want %d", path, len(array), want))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must match JSON array lengths", errors.Join(errs...))
+	}
+	return nil
+}
+
+// ignoredField replaces the ignored fields of the JSON documents before they
+// are compared.
+const ignoredField = "__ignored__"
+
+func assertJSONEqual(out string, w Assertion) error {
+	if w.JSON == "" {
+		return nil
+	}
+
+	var got, want interface{}
+	if err := json.Unmarshal([]byte(w.JSON), &want); err != nil {
+		return NewPrefixedError("must equal JSON", fmt.Errorf("expected JSON is not valid: %w", err))
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		return NewPrefixedError("must equal JSON",
+			fmt.Errorf("standard output is not valid JSON: %w: \"%s\"", err, out),
+		)
+	}
+
+	got, want = ignoreFields(got, w.JSONIgnoreFields), ignoreFields(want, w.JSONIgnoreFields)
+	if reflect.DeepEqual(got, want) {
+		return nil
+	}
+
+	// The documents are encoded back with their keys sorted, which makes
+	// them easier to compare.
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	return NewPrefixedError("must equal JSON", fmt.Errorf(
+		"got %s want %s", gotJSON, wantJSON,
+	))
+}
+
+// ignoreFields replaces the values found in the paths with a placeholder,
+// skipping the paths which aren't found.
+func ignoreFields(doc interface{}, paths []string) interface{} {
+	for _, path := range paths {
+		if _, err := jsonpath.Get(doc, path); err != nil {
+			continue
+		}
+		if updated, err := jsonpath.Set(doc, path, ignoredField); err == nil {
+			doc = updated
+		}
+	}
+	return doc
+}
+
+func assertOnlyJSON(stdout, stderr string, w Assertion) error {
+	var errs []error
+	if w.StdoutOnlyJSON && !json.Valid([]byte(stdout)) {
+		errs = append(errs, fmt.Errorf("%s is not only JSON: \"%s\"", Stdout, stdout))
+	}
+	if w.StderrOnlyJSON && !json.Valid([]byte(stderr)) {
+		errs = append(errs, fmt.Errorf("%s is not only JSON: \"%s\"", Stderr, stderr))
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must only be JSON", errors.Join(errs...))
+	}
+	return nil
+}
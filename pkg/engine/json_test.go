@@ -0,0 +1,181 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_assertJSONArrayLen(t *testing.T) {
+	const out = `{"items": [{"id": 1}, {"id": 2}], "meta": {"tags": []}, "count": 2}`
+	tests := []struct {
+		name    string
+		out     string
+		lengths map[string]int
+		err     string
+	}{
+		{
+			name:    "Passes when the arrays have the lengths",
+			out:     out,
+			lengths: map[string]int{"items": 2, "meta.tags": 0},
+		},
+		{
+			name:    "Fails when the array length differs",
+			out:     out,
+			lengths: map[string]int{"items": 3},
+			err:     "array in path \"items\" has 2 elements, want 3",
+		},
+		{
+			name:    "Fails when the value isn't an array",
+			out:     out,
+			lengths: map[string]int{"count": 2},
+			err:     "value in path \"count\" is not an array",
+		},
+		{
+			name:    "Fails when the path isn't found",
+			out:     out,
+			lengths: map[string]int{"hrefs": 2},
+			err:     "path \"hrefs\" not found",
+		},
+		{
+			name:    "Fails when the output isn't JSON",
+			out:     "not json",
+			lengths: map[string]int{"items": 2},
+			err:     "standard output is not valid JSON",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertJSONArrayLen(tt.out, tt.lengths)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertJSONArrayLen() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertJSONArrayLen() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertJSONEqual(t *testing.T) {
+	const out = `{"id": "f3a9", "name": "cluster", "created": "2023-01-02T15:04:05Z", "nodes": [{"id": "n1", "zone": "a"}]}`
+	tests := []struct {
+		name string
+		out  string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "Passes when the documents are equal ignoring the fields",
+			out:  out,
+			w: Assertion{
+				JSON:             `{"name": "cluster", "nodes": [{"zone": "a", "id": "*"}], "id": "*", "created": "*"}`,
+				JSONIgnoreFields: []string{"id", "created", "nodes[0].id"},
+			},
+		},
+		{
+			name: "Fails when an ignored field is missing",
+			out:  `{"name": "cluster"}`,
+			w: Assertion{
+				JSON:             `{"name": "cluster", "id": "*"}`,
+				JSONIgnoreFields: []string{"id"},
+			},
+			err: `got {"name":"cluster"} want {"id":"__ignored__","name":"cluster"}`,
+		},
+		{
+			name: "Fails when the documents differ",
+			out:  out,
+			w: Assertion{
+				JSON:             `{"id": "*", "name": "other", "created": "*", "nodes": [{"id": "*", "zone": "a"}]}`,
+				JSONIgnoreFields: []string{"id", "created", "nodes[0].id"},
+			},
+			err: `got {"created":"__ignored__","id":"__ignored__","name":"cluster","nodes":[{"id":"__ignored__","zone":"a"}]} ` +
+				`want {"created":"__ignored__","id":"__ignored__","name":"other","nodes":[{"id":"__ignored__","zone":"a"}]}`,
+		},
+		{
+			name: "Fails when the output isn't JSON",
+			out:  "not json",
+			w:    Assertion{JSON: `{}`},
+			err:  "standard output is not valid JSON",
+		},
+		{
+			name: "Fails when the expected document isn't JSON",
+			out:  out,
+			w:    Assertion{JSON: `{`},
+			err:  "expected JSON is not valid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertJSONEqual(tt.out, tt.w)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertJSONEqual() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertJSONEqual() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertOnlyJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		w      Assertion
+		stdout string
+		stderr string
+		err    string
+	}{
+		{
+			name:   "Passes when the standard output is JSON regardless of the standard error",
+			w:      Assertion{StdoutOnlyJSON: true},
+			stdout: "{\"status\": \"ok\"}\n",
+			stderr: "INFO request sent\n",
+		},
+		{
+			name:   "Passes when the standard error is JSON regardless of the standard output",
+			w:      Assertion{StderrOnlyJSON: true},
+			stdout: "status: ok\n",
+			stderr: "[{\"level\": \"info\"}]\n",
+		},
+		{
+			name:   "Fails when the standard output has logs",
+			w:      Assertion{StdoutOnlyJSON: true},
+			stdout: "INFO request sent\n{\"status\": \"ok\"}\n",
+			err:    "standard output is not only JSON: \"INFO request sent\n{\"status\": \"ok\"}\n\"",
+		},
+		{
+			name:   "Fails when the standard error isn't JSON",
+			w:      Assertion{StderrOnlyJSON: true},
+			stderr: "INFO request sent\n",
+			err:    "standard error is not only JSON",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertOnlyJSON(tt.stdout, tt.stderr, tt.w)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertOnlyJSON() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertOnlyJSON() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
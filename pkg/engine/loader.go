@@ -0,0 +1,104 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadTests decodes a YAML list of tests. The keys are the lowercased names
+// of the Test fields (e.g. "findbinary", "dynamicargs", "canerrorwithmessage").
+// Callbacks are referenced by the name they were registered with through
+// RegisterCallback, unknown keys or callback names cause an error.
+func LoadTests(r io.Reader) (Tests, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	var tests Tests
+	if err := dec.Decode(&tests); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return tests, nil
+}
+
+// LoadTestsFile opens the file found in the specified path and decodes its
+// tests with LoadTests.
+func LoadTestsFile(path string) (Tests, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tests, err := LoadTests(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading tests from %s: %w", path, err)
+	}
+	return tests, nil
+}
+
+// specFilePattern matches the files which RunDir loads the tests from.
+const specFilePattern = "*.test.yaml"
+
+// RunDir loads the tests of each of the "*.test.yaml" files found in the
+// directory and executes them with ExecuteTests, grouped in a subtest per
+// file. A file which fails to load only fails its own group.
+func RunDir(t *testing.T, dir string, opts ...Option) {
+	failLabel := newConfig(opts...).failLabel()
+	files, err := filepath.Glob(filepath.Join(dir, specFilePattern))
+	if err != nil {
+		t.Fatalf("[%s]: %s", failLabel, err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("[%s]: no %s spec files found in %s", failLabel, specFilePattern, dir)
+	}
+
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			tests, err := LoadTestsFile(file)
+			if err != nil {
+				t.Fatalf("[%s]: %s", failLabel, err)
+			}
+			ExecuteTests(t, tests, opts...)
+		})
+	}
+}
+
+// UnmarshalYAML decodes a map of storage keys to registered callback names
+// into the actual callbacks.
+func (tc *TestCallback) UnmarshalYAML(value *yaml.Node) error {
+	var refs map[string]string
+	if err := value.Decode(&refs); err != nil {
+		return err
+	}
+
+	resolved, err := ResolveCallbacks(refs)
+	if err != nil {
+		return err
+	}
+	*tc = resolved
+	return nil
+}
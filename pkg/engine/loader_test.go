@@ -0,0 +1,151 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func TestLoadTests(t *testing.T) {
+	RegisterCallback("store_output", func(out []byte, key string, storage teststorage.Storage) error {
+		storage.Set(key, string(out))
+		return nil
+	})
+
+	tests := []struct {
+		name          string
+		spec          string
+		want          Tests
+		wantCallbacks []string
+		err           string
+	}{
+		{
+			name: "Loads a list of tests",
+			spec: `
+- name: echo something
+  binary: echo
+  parallel: true
+  waitbeforerun: 1s
+  args:
+    args: ["something"]
+    dynamicargs: ["akey"]
+  assert:
+    canerrorwithmessage: ["known error"]
+    must:
+      output: ["something"]
+    not:
+      errors: ["failure"]
+`,
+			want: Tests{{
+				Name:          "echo something",
+				Binary:        "echo",
+				Parallel:      true,
+				WaitBeforeRun: time.Second,
+				Args: Args{
+					Args:        []string{"something"},
+					DynamicArgs: []string{"akey"},
+				},
+				Assert: Assertions{
+					CanErrorWithMessage: []string{"known error"},
+					Must:                Assertion{Output: []string{"something"}},
+					Not:                 Assertion{Errors: []string{"failure"}},
+				},
+			}},
+		},
+		{
+			name: "Resolves registered callbacks",
+			spec: `
+- name: store
+  binary: echo
+  callbacks:
+    stored_key: store_output
+`,
+			wantCallbacks: []string{"stored_key"},
+		},
+		{
+			name: "Fails on unregistered callbacks",
+			spec: `
+- name: store
+  binary: echo
+  callbacks:
+    stored_key: unregistered
+`,
+			err: "callback \"unregistered\" for key \"stored_key\" is not registered",
+		},
+		{
+			name: "Fails on unknown fields",
+			spec: `
+- name: store
+  binaryname: echo
+`,
+			err: "field binaryname not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadTests(strings.NewReader(tt.spec))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("LoadTests() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantCallbacks != nil {
+				var keys []string
+				for key := range got[0].Callbacks {
+					keys = append(keys, key)
+				}
+				if !reflect.DeepEqual(keys, tt.wantCallbacks) {
+					t.Errorf("LoadTests() callbacks = %v, want %v", keys, tt.wantCallbacks)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadTests() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunDir(t *testing.T) {
+	dir := t.TempDir()
+	specs := map[string]string{
+		"echo.test.yaml": "- name: echo\n  binary: echo\n  assert:\n    must:\n      emptyoutput: true\n",
+		"true.test.yaml": "- name: true\n  binary: \"true\"\n",
+		"ignored.yaml":   "- name: ignored\n  binary: \"false\"\n",
+	}
+	for name, spec := range specs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(spec), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	RunDir(t, dir, WithCooldown(0))
+}
@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+)
+
+// mockBinEnv passes the path of the mock command to the subprocesses of the
+// test binary, so they don't build it again.
+const mockBinEnv = "TESTCLI_TEST_MOCK_BIN"
+
+// mockBin is the path of the mock command the tests run, which is built once
+// for the whole package.
+var mockBin string
+
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	if mockBin = os.Getenv(mockBinEnv); mockBin != "" {
+		return m.Run()
+	}
+
+	dir, err := os.MkdirTemp("", "testcli-mock-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	if mockBin, err = enginetest.BuildTo(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Setenv(mockBinEnv, mockBin)
+	return m.Run()
+}
+
+// mockArgs returns the arguments which make the mock command behave as
+// configured.
+func mockArgs(m enginetest.MockCommand) Args {
+	return Args{Args: m.Args()}
+}
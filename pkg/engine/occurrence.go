@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OccurrenceContext ensures that a value is found around the Nth occurrence
+// of an anchor, such as the warning which follows the third "processing"
+// line. Without a window, the value must be on the same line as the anchor.
+type OccurrenceContext struct {
+	// Substring which is searched for.
+	Anchor string
+
+	// 1-based number of the anchor occurrence. Defaults to the first one.
+	N int
+
+	// Substring which must be found around the occurrence.
+	Want string
+
+	// Number of lines before and after the line of the occurrence which
+	// are searched. Takes precedence over WithinChars.
+	WithinLines int
+
+	// Number of characters before and after the occurrence which are
+	// searched.
+	WithinChars int
+}
+
+// window returns the text around the occurrence at the offset.
+func (c OccurrenceContext) window(out string, offset int) string {
+	if c.WithinLines <= 0 && c.WithinChars > 0 {
+		start, end := offset-c.WithinChars, offset+len(c.Anchor)+c.WithinChars
+		if start < 0 {
+			start = 0
+		}
+		if end > len(out) {
+			end = len(out)
+		}
+		return out[start:end]
+	}
+
+	lines := strings.Split(out, "\n")
+	first := strings.Count(out[:offset], "\n")
+	last := first + strings.Count(c.Anchor, "\n")
+	start, end := first-c.WithinLines, last+c.WithinLines+1
+	if start < 0 {
+		start = 0
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+// nthIndex returns the offset of the nth occurrence of substr, or -1 along
+// with the number of occurrences when there are fewer.
+func nthIndex(s, substr string, n int) (int, int) {
+	var offset, found int
+	for found < n {
+		i := strings.Index(s[offset:], substr)
+		if i < 0 {
+			return -1, found
+		}
+		found++
+		if found == n {
+			return offset + i, found
+		}
+		offset += i + len(substr)
+	}
+	return -1, found
+}
+
+func assertOccurrences(out string, occurrences []OccurrenceContext) error {
+	var errs []error
+	for _, c := range occurrences {
+		n := c.N
+		if n <= 0 {
+			n = 1
+		}
+		if c.Anchor == "" {
+			errs = append(errs, errors.New("occurrence anchor can't be empty"))
+			continue
+		}
+
+		offset, found := nthIndex(out, c.Anchor, n)
+		if offset < 0 {
+			errs = append(errs, fmt.Errorf("found %d occurrences of \"%s\", want at least %d", found, c.Anchor, n))
+			continue
+		}
+
+		if window := c.window(out, offset); !strings.Contains(window, c.Want) {
+			errs = append(errs, fmt.Errorf("didn't find \"%s\" around occurrence %d of \"%s\": \"%s\"",
+				c.Want, n, c.Anchor, window,
+			))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must find around occurrence", errors.Join(errs...))
+	}
+	return nil
+}
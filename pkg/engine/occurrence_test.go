@@ -0,0 +1,90 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_assertOccurrences(t *testing.T) {
+	const out = "processing a\nok\nprocessing b\nok\nprocessing c\nwarning: c skipped\nprocessing d\n"
+	tests := []struct {
+		name        string
+		occurrences []OccurrenceContext
+		err         string
+	}{
+		{
+			name: "finds the value in the lines after the occurrence",
+			occurrences: []OccurrenceContext{
+				{Anchor: "processing", N: 3, Want: "skipped", WithinLines: 1},
+			},
+		},
+		{
+			name: "finds the value on the line of the occurrence",
+			occurrences: []OccurrenceContext{
+				{Anchor: "processing", N: 2, Want: "b"},
+			},
+		},
+		{
+			name: "finds the value within the characters around the occurrence",
+			occurrences: []OccurrenceContext{
+				{Anchor: "warning", Want: "c\n", WithinChars: 2},
+			},
+		},
+		{
+			name: "defaults to the first occurrence",
+			occurrences: []OccurrenceContext{
+				{Anchor: "processing", Want: "a"},
+			},
+		},
+		{
+			name: "fails when the value isn't within the window",
+			occurrences: []OccurrenceContext{
+				{Anchor: "processing", N: 2, Want: "skipped", WithinLines: 1},
+			},
+			err: "didn't find \"skipped\" around occurrence 2 of \"processing\": \"ok\nprocessing b\nok\"",
+		},
+		{
+			name: "fails when there are fewer occurrences",
+			occurrences: []OccurrenceContext{
+				{Anchor: "processing", N: 5, Want: "e"},
+			},
+			err: "found 4 occurrences of \"processing\", want at least 5",
+		},
+		{
+			name:        "fails on an empty anchor",
+			occurrences: []OccurrenceContext{{Want: "ok"}},
+			err:         "occurrence anchor can't be empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertOccurrences(out, tt.occurrences)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("assertOccurrences() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("assertOccurrences() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
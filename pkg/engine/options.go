@@ -0,0 +1,244 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// NoCooldownEnv is the environment variable which disables the cooldown after
+// each test when it's set to a true value, such as "1" or "true".
+const NoCooldownEnv = "TESTCLI_NO_COOLDOWN"
+
+// defaultOutputLimit is the default maximum length of the output included in
+// the failure messages.
+const defaultOutputLimit = 8 << 10
+
+// Option configures how a suite of tests is executed.
+type Option func(*config)
+
+// config holds the suite settings and state shared by all the tests.
+type config struct {
+	// Maximum length of the standard output and error included in the failure
+	// messages. A value <= 0 disables the limit.
+	outputLimit int
+
+	// When set, the execution details of every test are logged.
+	verbose bool
+
+	// When set, the complete output of the failed tests is logged.
+	outputOnFailure bool
+
+	// When set, a summary of the passing tests is logged.
+	logOnSuccess bool
+
+	// Base period of the random cooldown after each test. A value <= 0
+	// disables the cooldown.
+	cooldown time.Duration
+
+	// When set, the binary must report this version before any test runs.
+	requireVersion *RequireVersion
+
+	// Path of the file where the names of the failed tests are cached.
+	failureCache string
+
+	// When set, only the tests found in the failure cache are run.
+	runFailedOnly bool
+
+	// When set, the storage keys of each test are namespaced by its name.
+	namespacedStorage bool
+
+	// When set, the output of the commands is copied to os.Stdout and
+	// os.Stderr as it's written.
+	liveOutput bool
+
+	// When set, the outcome of each test is recorded in it.
+	report *Report
+
+	// When set, the failure and warning labels are colored.
+	color bool
+
+	// When set, limits the total number of retries of the suite.
+	retryBudget *retryBudget
+
+	// When set, the cooldown is scaled by the number of running tests.
+	adaptiveCooldown bool
+
+	// Number of tests which are running, shared by the copies of the config.
+	running *atomic.Int64
+
+	// Times the tests and their delays.
+	clock Clock
+
+	// Cancels the tests, the running commands are killed once it's done.
+	ctx context.Context
+
+	// Tracks the tests which other tests depend on.
+	deps *dependencies
+
+	// Tracks the tests which failed, nil when there's no failure cache.
+	failures *failureCache
+}
+
+func newConfig(opts ...Option) config {
+	cfg := config{
+		outputLimit: defaultOutputLimit,
+		cooldown:    defaultCooldownPeriod,
+		clock:       realClock{},
+		ctx:         context.Background(),
+		color:       isTerminal(os.Stdout),
+		running:     new(atomic.Int64),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// The environment variable takes precedence over the options, so the
+	// cooldown can be disabled without changing the code.
+	if noCooldown, _ := strconv.ParseBool(os.Getenv(NoCooldownEnv)); noCooldown {
+		cfg.cooldown = 0
+	}
+	if os.Getenv(NoColorEnv) != "" {
+		cfg.color = false
+	}
+	return cfg
+}
+
+// wait blocks for the duration on the clock, or until the context is done.
+func (c config) wait(d time.Duration) {
+	select {
+	case <-c.clock.After(d):
+	case <-c.ctx.Done():
+	}
+}
+
+// cooldownPeriod returns a random delay of 1 to 9 times the cooldown period.
+func (c config) cooldownPeriod() time.Duration {
+	if c.cooldown <= 0 {
+		return 0
+	}
+	return c.cooldownBase() * time.Duration(rand.Intn(9)+1)
+}
+
+// cooldownBase returns the cooldown period, which is multiplied by the number
+// of running tests plus one when the cooldown is adaptive, so the test which
+// is cooling down counts along with the ones still running.
+func (c config) cooldownBase() time.Duration {
+	if !c.adaptiveCooldown || c.running == nil {
+		return c.cooldown
+	}
+	return c.cooldown * time.Duration(c.running.Load()+1)
+}
+
+// start counts a test as running until the returned function is called.
+func (c config) start() (done func()) {
+	if c.running == nil {
+		return func() {}
+	}
+	c.running.Add(1)
+	return func() { c.running.Add(-1) }
+}
+
+// WithOutputLimit caps the length of the standard output and error included in
+// the failure messages, keeping the head and tail of longer outputs around an
+// elision marker. A limit <= 0 includes the outputs in full. The limit doesn't
+// affect the captured output which the assertions and callbacks receive.
+// Defaults to 8KB.
+func WithOutputLimit(limit int) Option {
+	return func(c *config) { c.outputLimit = limit }
+}
+
+// WithVerbose logs the resolved binary, the redacted command, the working
+// directory, the names of the environment variables which are set and unset,
+// the exit code and the duration of every test through t.Log.
+func WithVerbose() Option {
+	return func(c *config) { c.verbose = true }
+}
+
+// WithOutputOnFailure logs the complete standard output and error of the tests
+// which fail through t.Log, regardless of whether they failed on an assertion
+// or on a callback. The output of the tests which pass isn't logged.
+func WithOutputOnFailure() Option {
+	return func(c *config) { c.outputOnFailure = true }
+}
+
+// WithFailureCache writes the names of the tests which fail to the file found
+// in the path once the suite has finished. See WithRunFailedOnly.
+func WithFailureCache(path string) Option {
+	return func(c *config) { c.failureCache = path }
+}
+
+// WithRunFailedOnly skips the tests which aren't in the failure cache, except
+// the ones which the cached tests depend on, so only the tests which failed on
+// the last run are run again. All the tests are run when the cache doesn't
+// exist yet. It has no effect without WithFailureCache.
+func WithRunFailedOnly() Option {
+	return func(c *config) { c.runFailedOnly = true }
+}
+
+// WithNamespacedStorage prefixes the storage keys which each test reads and
+// writes with its sanitized name, so tests running in parallel can't clobber
+// each other's values. Keys starting with teststorage.GlobalKeyPrefix aren't
+// prefixed, which allows chaining values between tests deliberately.
+func WithNamespacedStorage() Option {
+	return func(c *config) { c.namespacedStorage = true }
+}
+
+// WithLiveOutput copies the standard output and error of the commands to
+// os.Stdout and os.Stderr as they're written, which is useful to watch long
+// tests locally. The output of parallel tests is interleaved. It's replaced
+// by the OutputWriter and ErrorWriter of the tests which set them.
+func WithLiveOutput() Option {
+	return func(c *config) { c.liveOutput = true }
+}
+
+// WithoutColor omits the ANSI color codes from the failure and warning labels.
+// The labels are only colored when the standard output is a terminal anyway,
+// and never when the NO_COLOR environment variable is set.
+func WithoutColor() Option {
+	return func(c *config) { c.color = false }
+}
+
+// WithAdaptiveCooldown scales the cooldown after each test by the number of
+// tests which are still running, so the cooldown grows along with the
+// parallelism and stays as short as WithCooldown when the tests run one at a
+// time.
+func WithAdaptiveCooldown() Option {
+	return func(c *config) { c.adaptiveCooldown = true }
+}
+
+// WithLogOnSuccess logs the redacted command, the exit code and the duration
+// of the tests which pass through t.Log, leaving a lightweight execution trail
+// without the details of WithVerbose. It doesn't affect WithOutputOnFailure.
+func WithLogOnSuccess() Option {
+	return func(c *config) { c.logOnSuccess = true }
+}
+
+// WithCooldown sets the base period of the random cooldown after each test,
+// which is delayed 1 to 9 times the period so the tests don't choke the
+// machine where they are running. A period <= 0 disables the cooldown.
+// Defaults to 100ms. Setting the TESTCLI_NO_COOLDOWN environment variable to
+// a true value disables the cooldown regardless of this option.
+func WithCooldown(period time.Duration) Option {
+	return func(c *config) { c.cooldown = period }
+}
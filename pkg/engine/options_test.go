@@ -0,0 +1,221 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func Test_newConfig_cooldown(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		opts []Option
+		want time.Duration
+	}{
+		{
+			name: "Defaults to the default cooldown period",
+			want: defaultCooldownPeriod,
+		},
+		{
+			name: "Uses the cooldown option",
+			opts: []Option{WithCooldown(time.Second)},
+			want: time.Second,
+		},
+		{
+			name: "Disables the cooldown with the environment variable",
+			env:  "1",
+			opts: []Option{WithCooldown(time.Second)},
+		},
+		{
+			name: "Ignores the environment variable when it's false",
+			env:  "false",
+			want: defaultCooldownPeriod,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(NoCooldownEnv, tt.env)
+			if got := newConfig(tt.opts...).cooldown; got != tt.want {
+				t.Errorf("newConfig() cooldown = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_config_cooldownBase(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		running int
+		want    time.Duration
+	}{
+		{
+			name:    "Ignores the running tests by default",
+			opts:    []Option{WithCooldown(time.Second)},
+			running: 3,
+			want:    time.Second,
+		},
+		{
+			name: "Keeps the cooldown when no other test is running",
+			opts: []Option{WithCooldown(time.Second), WithAdaptiveCooldown()},
+			want: time.Second,
+		},
+		{
+			name:    "Scales the cooldown with the running tests",
+			opts:    []Option{WithCooldown(time.Second), WithAdaptiveCooldown()},
+			running: 3,
+			want:    4 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(NoCooldownEnv, "")
+			cfg := newConfig(tt.opts...)
+			for i := 0; i < tt.running; i++ {
+				defer cfg.start()()
+			}
+			if got := cfg.cooldownBase(); got != tt.want {
+				t.Errorf("cooldownBase() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithOutputOnFailure(t *testing.T) {
+	fail := func([]byte, string, teststorage.Storage) error { return errors.New("callback failed") }
+	tests := Tests{
+		{
+			Name:   "passing",
+			Binary: mockBin,
+			Args:   mockArgs(enginetest.MockCommand{Stdout: "passing output\n"}),
+		},
+		{
+			Name:      "failing",
+			Binary:    mockBin,
+			Args:      mockArgs(enginetest.MockCommand{Stdout: "failing output --pass secret\n", Stderr: "failing error\n"}),
+			Callbacks: TestCallback{"output_on_failure": fail},
+		},
+		{
+			Name:           "fatal",
+			Binary:         mockBin,
+			Args:           mockArgs(enginetest.MockCommand{Stdout: "fatal output\n"}),
+			OutputEncoding: "unexisting-encoding",
+		},
+	}
+	if inSubprocess() {
+		ExecuteTests(t, tests, WithOutputOnFailure(), WithCooldown(0))
+		return
+	}
+
+	out := runInSubprocess(t)
+	for _, want := range []string{
+		"callback failed",
+		"[Test 1]: standard output:", "failing output --pass [REDACTED]",
+		"[Test 1]: standard error:", "failing error",
+		// The output is logged when the test fails before it's asserted.
+		"[Test 2]: standard output:", "fatal output",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %s, want it to contain %q", out, want)
+		}
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("output = %s, want the password redacted", out)
+	}
+	if strings.Contains(out, "[Test 0]: standard output") {
+		t.Errorf("output = %s, want the passing test's output not to be logged", out)
+	}
+}
+
+func TestWithVerbose(t *testing.T) {
+	t.Setenv("TESTCLI_TEST_TOKEN", "secret")
+	tests := Tests{{
+		Name:     "verbose",
+		Binary:   mockBin,
+		Args:     mockArgs(enginetest.MockCommand{}),
+		TempDir:  true,
+		Env:      map[string]string{"TESTCLI_TEST_API_KEY": "s3cr3t", "TESTCLI_TEST_OUT": TempDirPlaceholder},
+		UnsetEnv: []string{"TESTCLI_TEST_TOKEN", "HOME"},
+	}}
+	if inSubprocess() {
+		ExecuteTests(t, tests, WithVerbose(), WithCooldown(0))
+		return
+	}
+
+	out := runInSubprocess(t)
+	for _, want := range []string{
+		"[Test 0]: binary: " + mockBin,
+		"[Test 0]: environment: TESTCLI_TEST_API_KEY=[REDACTED] TESTCLI_TEST_OUT=[REDACTED]",
+		"[Test 0]: unset environment: TESTCLI_TEST_TOKEN HOME",
+		"[Test 0]: exit code: 0",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %s, want it to contain %q", out, want)
+		}
+	}
+	for _, secret := range []string{"s3cr3t", "secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("output = %s, want %q redacted", out, secret)
+		}
+	}
+}
+
+func TestWithLogOnSuccess(t *testing.T) {
+	// The mock command ignores the arguments after the "--".
+	passing := append(enginetest.MockCommand{}.Args(), "--", "--pass", "secret")
+	tests := []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{name: "enabled", opts: []Option{WithLogOnSuccess()}, want: true},
+		{name: "disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if inSubprocess() {
+				ExecuteTests(t, Tests{{
+					Name:   "passing",
+					Binary: mockBin,
+					Args:   Args{Args: passing},
+				}}, append(tt.opts, WithCooldown(0))...)
+				return
+			}
+
+			out := runInSubprocess(t)
+			command := strings.Join(append([]string{mockBin}, passing...), " ")
+			logged := "[Test 0]: passed: " + redactPasswordFlag(command) + ", exit code: 0, duration:"
+			if got := strings.Contains(out, logged); got != tt.want {
+				t.Errorf("output = %s, want logged = %v", out, tt.want)
+			}
+			if strings.Contains(out, "secret") {
+				t.Errorf("output = %s, want the password redacted", out)
+			}
+			if !strings.Contains(out, "--- PASS") {
+				t.Errorf("output = %s, want the test to pass", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReadyTimeout = 30 * time.Second
+
+	// The polling of WaitForReady backs off from the interval up to the
+	// maximum one, with a jitter so parallel tests don't poll in lockstep.
+	defaultReadyInterval    = 500 * time.Millisecond
+	defaultReadyMaxInterval = 5 * time.Second
+	defaultReadyJitter      = 0.2
+)
+
+// CommandReady returns a readiness check for Test.WaitForReady, which is ready
+// once the command exits successfully. The command is killed once the context
+// is done, so a hanging command can't outlast the ReadyTimeout.
+func CommandReady(name string, args ...string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var output bytes.Buffer
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout, cmd.Stderr = &output, &output
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("command \"%s\" failed: %w: %s",
+				strings.Join(append([]string{name}, args...), " "), err, output.String(),
+			)
+		}
+		return nil
+	}
+}
+
+// waitForReady polls the ready function until it returns nil, waiting the
+// delays of the backoff between the calls. It returns the function's last
+// error when the timeout is exceeded, or as soon as the context is done. Each
+// call to the ready function is given the time left until the timeout.
+func waitForReady(ctx context.Context, clock Clock, ready func(ctx context.Context) error, timeout time.Duration, backoff Backoff) error {
+	deadline := clock.Now().Add(timeout)
+	var err error
+	for attempt := 0; ; attempt++ {
+		// The function is always called once, but never without time left.
+		remaining := deadline.Sub(clock.Now())
+		if err != nil && remaining <= 0 {
+			return fmt.Errorf("not ready after %s: %w", timeout, err)
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, remaining)
+		err = ready(checkCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() == nil {
+			select {
+			case <-clock.After(backoff.delay(attempt, rand.Float64)):
+				continue
+			case <-ctx.Done():
+			}
+		}
+		return fmt.Errorf("stopped waiting until ready: %w: %v", ctx.Err(), err)
+	}
+}
@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+)
+
+func Test_waitForReady(t *testing.T) {
+	refused := enginetest.MockCommand{Stderr: "connection refused\n", ExitCode: 2}.Args()
+	hanging := enginetest.MockCommand{Delay: 10 * time.Second}.Args()
+	notReady := errors.New("not ready yet")
+	readyAfter := func(attempts int) func(context.Context) error {
+		return func(context.Context) error {
+			if attempts--; attempts > 0 {
+				return notReady
+			}
+			return nil
+		}
+	}
+	tests := []struct {
+		name    string
+		ready   func(context.Context) error
+		timeout time.Duration
+		err     string
+	}{
+		{
+			name:    "Returns once the function is ready",
+			ready:   readyAfter(3),
+			timeout: time.Second,
+		},
+		{
+			name:    "Returns the last error after the timeout",
+			ready:   readyAfter(1000),
+			timeout: 10 * time.Millisecond,
+			err:     "not ready after 10ms: not ready yet",
+		},
+		{
+			name:    "Returns once the command succeeds",
+			ready:   CommandReady(mockBin, enginetest.MockCommand{}.Args()...),
+			timeout: time.Second,
+		},
+		{
+			name:    "Returns the command error after the timeout",
+			ready:   CommandReady(mockBin, refused...),
+			timeout: 10 * time.Millisecond,
+			err:     "command \"" + strings.Join(append([]string{mockBin}, refused...), " ") + "\" failed",
+		},
+		{
+			name:    "Kills the command once the timeout is exceeded",
+			ready:   CommandReady(mockBin, hanging...),
+			timeout: 10 * time.Millisecond,
+			err:     "command \"" + strings.Join(append([]string{mockBin}, hanging...), " ") + "\" failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := waitForReady(context.Background(), &fakeClock{}, tt.ready, tt.timeout, Backoff{Base: time.Millisecond})
+			if tt.err == "" && err != nil {
+				t.Errorf("waitForReady() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("waitForReady() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_waitForReady_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int
+	ready := func(context.Context) error {
+		if calls++; calls == 2 {
+			cancel()
+		}
+		return errors.New("not ready yet")
+	}
+
+	err := waitForReady(ctx, &fakeClock{}, ready, time.Hour, Backoff{Base: time.Millisecond})
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "not ready yet") {
+		t.Errorf("waitForReady() error = %v, want %v", err, context.Canceled)
+	}
+	if calls != 2 {
+		t.Errorf("waitForReady() called the function %d times, want 2", calls)
+	}
+}
+
+func Test_waitForReady_backoff(t *testing.T) {
+	clock := &fakeClock{}
+	var calls []time.Time
+	ready := func(context.Context) error {
+		if calls = append(calls, clock.Now()); len(calls) < 5 {
+			return errors.New("not ready yet")
+		}
+		return nil
+	}
+
+	if err := waitForReady(context.Background(), clock, ready, time.Hour, Backoff{Base: time.Second, Max: 4 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+
+	var intervals []time.Duration
+	for i := 1; i < len(calls); i++ {
+		intervals = append(intervals, calls[i].Sub(calls[i-1]))
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	if !reflect.DeepEqual(intervals, want) {
+		t.Errorf("waitForReady() polled after %v, want %v", intervals, want)
+	}
+}
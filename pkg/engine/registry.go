@@ -0,0 +1,82 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var registry = struct {
+	callbacks map[string]Callback
+	sync.RWMutex
+}{callbacks: make(map[string]Callback)}
+
+// RegisterCallback registers a Callback under a name, so it can be referenced
+// from declarative test specs (See LoadTests). Registering a callback with an
+// existing name replaces the previous one.
+func RegisterCallback(name string, cb Callback) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.callbacks[name] = cb
+}
+
+// LookupCallback obtains a registered callback by its name and returns whether
+// or not it was found.
+func LookupCallback(name string) (Callback, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	cb, ok := registry.callbacks[name]
+	return cb, ok
+}
+
+// ResolveCallbacks resolves a map of storage keys to registered callback names
+// into a TestCallback. An error is returned when any of the callback names has
+// not been registered.
+func ResolveCallbacks(refs map[string]string) (TestCallback, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	// Sort the keys so the returned errors are stable.
+	keys := make([]string, 0, len(refs))
+	for key := range refs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	tc := make(TestCallback, len(refs))
+	for _, key := range keys {
+		cb, ok := LookupCallback(refs[key])
+		if !ok {
+			errs = append(errs, fmt.Errorf(
+				"callback \"%s\" for key \"%s\" is not registered", refs[key], key,
+			))
+			continue
+		}
+		tc[key] = cb
+	}
+
+	if len(errs) > 0 {
+		return nil, NewPrefixedError("resolve callbacks", errors.Join(errs...))
+	}
+	return tc, nil
+}
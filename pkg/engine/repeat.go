@@ -0,0 +1,39 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import "fmt"
+
+// assertOutputGrowth ensures that none of the output sizes of the repeated
+// runs grows beyond the tolerance from the size of the first run.
+func assertOutputGrowth(sizes []int, tolerance float64) error {
+	if len(sizes) < 2 {
+		return nil
+	}
+
+	limit := float64(sizes[0]) * (1 + tolerance)
+	for i, size := range sizes[1:] {
+		if float64(size) > limit {
+			return NewPrefixedError("must not grow across repeats", fmt.Errorf(
+				"run %d output is %d bytes, over the limit of %.0f bytes, run sizes: %v",
+				i+2, size, limit, sizes,
+			))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+)
+
+func Test_assertOutputGrowth(t *testing.T) {
+	tests := []struct {
+		name      string
+		sizes     []int
+		tolerance float64
+		err       string
+	}{
+		{
+			name:  "single run",
+			sizes: []int{100},
+		},
+		{
+			name:  "constant size",
+			sizes: []int{100, 100, 100},
+		},
+		{
+			name:  "smaller size",
+			sizes: []int{100, 90, 100},
+		},
+		{
+			name:      "growth within the tolerance",
+			sizes:     []int{100, 105, 110},
+			tolerance: 0.1,
+		},
+		{
+			name:      "growth beyond the tolerance",
+			sizes:     []int{100, 105, 120, 140},
+			tolerance: 0.1,
+			err:       "run 3 output is 120 bytes, over the limit of 110 bytes, run sizes: [100 105 120 140]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertOutputGrowth(tt.sizes, tt.tolerance)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("assertOutputGrowth() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("assertOutputGrowth() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestExecuteTests_repeat(t *testing.T) {
+	tests := Tests{
+		{
+			Name:   "constant output across repeats",
+			Binary: mockBin,
+			Args:   mockArgs(enginetest.MockCommand{Stdout: "deployment-a\n"}),
+			Repeat: 3,
+		},
+	}
+	ExecuteTests(t, tests, WithCooldown(0))
+}
@@ -0,0 +1,115 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestStatus is the outcome of a test.
+type TestStatus string
+
+const (
+	// StatusPassed is the status of a test which passed.
+	StatusPassed TestStatus = "passed"
+	// StatusFailed is the status of a test which failed.
+	StatusFailed TestStatus = "failed"
+	// StatusSkipped is the status of a test which was skipped.
+	StatusSkipped TestStatus = "skipped"
+	// StatusWarning is the status of a test which passed, but didn't meet
+	// some of its Should assertions.
+	StatusWarning TestStatus = "warning"
+)
+
+// TestReport holds the outcome of a test, with enough details to render its
+// failure without the test logs.
+type TestReport struct {
+	Name   string
+	Status TestStatus
+
+	// The redacted command, empty when the test didn't run it.
+	Command  string
+	ExitCode int
+	Duration time.Duration
+
+	// The output of the last attempt, redacted and elided to the output
+	// limit like in the failure messages.
+	Stdout string
+	Stderr string
+
+	// The unmet Should assertions, redacted like the output.
+	Warnings []string
+
+	// Position of the test in the order the tests were run.
+	n int
+}
+
+// Report collects the outcome of the tests as they finish. It's safe to use
+// from parallel tests.
+type Report struct {
+	mu    sync.Mutex
+	tests []TestReport
+}
+
+// add records the outcome of the test, deriving its status from t.
+func (r *Report) add(t *testing.T, entry TestReport) {
+	switch {
+	case t.Skipped():
+		entry.Status = StatusSkipped
+	case t.Failed():
+		entry.Status = StatusFailed
+	case len(entry.Warnings) > 0:
+		entry.Status = StatusWarning
+	default:
+		entry.Status = StatusPassed
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tests = append(r.tests, entry)
+}
+
+// Tests returns the outcome of the finished tests, in the order they were
+// run.
+func (r *Report) Tests() []TestReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tests := append([]TestReport(nil), r.tests...)
+	sort.SliceStable(tests, func(i, j int) bool { return tests[i].n < tests[j].n })
+	return tests
+}
+
+// WithReport records the outcome of each test in the report.
+func WithReport(r *Report) Option {
+	return func(c *config) { c.report = r }
+}
+
+// ExecuteTestsWithReport is like ExecuteTests, returning the report of the
+// tests. Since parallel tests only finish after the calling test function
+// returns, the report is complete once the cleanup functions of t run:
+//
+//	report := engine.ExecuteTestsWithReport(t, tests)
+//	t.Cleanup(func() { render(report.Tests()) })
+func ExecuteTestsWithReport(t *testing.T, tests Tests, opts ...Option) *Report {
+	report := new(Report)
+	ExecuteTests(t, tests, append(opts, WithReport(report))...)
+	return report
+}
@@ -0,0 +1,99 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+)
+
+func TestExecuteTestsWithReport(t *testing.T) {
+	tests := Tests{
+		{
+			Name:   "login",
+			Binary: mockBin,
+			Args:   mockArgs(enginetest.MockCommand{Stdout: "login --pass secret " + strings.Repeat("a", 100)}),
+		},
+		{
+			Name:       "skipped",
+			Binary:     mockBin,
+			RequireEnv: []string{"TESTCLI_TEST_UNSET_VARIABLE"},
+		},
+		{
+			Name:   "warned",
+			Binary: mockBin,
+			Args:   mockArgs(enginetest.MockCommand{Stdout: "cluster started\n"}),
+			Assert: Assertions{Should: Assertion{Output: []string{"cluster healthy"}}},
+		},
+	}
+	report := ExecuteTestsWithReport(t, tests, WithCooldown(0), WithOutputLimit(40))
+
+	got := report.Tests()
+	if len(got) != 3 {
+		t.Fatalf("Tests() = %+v, want 3 tests", got)
+	}
+
+	login := got[0]
+	if login.Name != "login" || login.Status != StatusPassed || login.ExitCode != 0 {
+		t.Errorf("Tests()[0] = %+v, want login to pass", login)
+	}
+	if !strings.Contains(login.Command, "--pass [REDACTED]") {
+		t.Errorf("Tests()[0].Command = %q, want it redacted", login.Command)
+	}
+	if strings.Contains(login.Stdout, "secret") {
+		t.Errorf("Tests()[0].Stdout = %q, want it redacted", login.Stdout)
+	}
+	if !strings.Contains(login.Stdout, "elided") || len(login.Stdout) > 100 {
+		t.Errorf("Tests()[0].Stdout = %q, want it elided", login.Stdout)
+	}
+
+	if skipped := got[1]; skipped.Name != "skipped" || skipped.Status != StatusSkipped || skipped.Command != "" {
+		t.Errorf("Tests()[1] = %+v, want it skipped", skipped)
+	}
+
+	warned := got[2]
+	if warned.Status != StatusWarning || len(warned.Warnings) != 1 ||
+		!strings.Contains(warned.Warnings[0], "didn't find \"cluster healthy\"") {
+		t.Errorf("Tests()[2] = %+v, want it warned", warned)
+	}
+}
+
+func TestExecuteTestsWithReport_failureCache(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "failures")
+	if err := os.WriteFile(cache, []byte("failed last time\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := Tests{
+		{Name: "failed last time", Binary: mockBin, Args: mockArgs(enginetest.MockCommand{})},
+		{Name: "passed last time", Binary: mockBin, Args: mockArgs(enginetest.MockCommand{})},
+	}
+	report := ExecuteTestsWithReport(t, tests, WithCooldown(0), WithFailureCache(cache), WithRunFailedOnly())
+
+	got := report.Tests()
+	if len(got) != 2 {
+		t.Fatalf("Tests() = %+v, want 2 tests", got)
+	}
+	if got[0].Status != StatusPassed || got[1].Name != "passed last time" || got[1].Status != StatusSkipped {
+		t.Errorf("Tests() = %+v, want the cached test to pass and the other one skipped", got)
+	}
+}
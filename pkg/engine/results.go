@@ -0,0 +1,67 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"sync"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+// Results collects the values decoded by the callbacks so they can be asserted
+// once the tests are run. It's safe to use from tests running in parallel,
+// unlike a variable which the callbacks close over. The zero value is ready to
+// use and must not be copied after its first use.
+type Results[T any] struct {
+	mu     sync.Mutex
+	values []T
+}
+
+// Add appends the value to the results.
+func (r *Results[T]) Add(v T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values = append(r.values, v)
+}
+
+// All returns a copy of the results, in the order they were added.
+func (r *Results[T]) All() []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]T(nil), r.values...)
+}
+
+// Len returns the number of results.
+func (r *Results[T]) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.values)
+}
+
+// Collect returns a callback which decodes the output and adds the decoded
+// value to the results.
+func Collect[T any](r *Results[T], decode func(out []byte) (T, error)) Callback {
+	return func(out []byte, _ string, _ teststorage.Storage) error {
+		v, err := decode(out)
+		if err != nil {
+			return err
+		}
+		r.Add(v)
+		return nil
+	}
+}
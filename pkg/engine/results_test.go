@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func TestResults(t *testing.T) {
+	var results Results[int]
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results.Add(i)
+		}(i)
+	}
+	wg.Wait()
+
+	if got := results.Len(); got != 100 {
+		t.Errorf("Results.Len() = %d, want 100", got)
+	}
+
+	// The returned slice is a copy.
+	all := results.All()
+	all[0] = -1
+	if got := results.All()[0]; got == -1 {
+		t.Error("Results.All() returned the underlying slice")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	var results Results[int]
+	callback := Collect(&results, func(out []byte) (int, error) {
+		return strconv.Atoi(string(out))
+	})
+
+	storage := teststorage.NewSafeMap()
+	if err := callback([]byte("42"), "key", storage); err != nil {
+		t.Fatal(err)
+	}
+	err := callback([]byte("not a number"), "key", storage)
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("Collect() error = %v, want %v", err, strconv.ErrSyntax)
+	}
+
+	if got := results.All(); !reflect.DeepEqual(got, []int{42}) {
+		t.Errorf("Collect() results = %v, want %v", got, []int{42})
+	}
+}
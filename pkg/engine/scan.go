@@ -0,0 +1,124 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+// ScanFormat parses the fields of a line with a fmt.Sscanf format, such as
+// "Created cluster %s in %s".
+type ScanFormat struct {
+	// Format which a line of the output must match. Supports the %s, %q, %v,
+	// %x, %d, %f, %g, %e and %t verbs.
+	Format string
+
+	// Storage keys where the parsed fields are stored, one for each verb in
+	// the format.
+	Into []string
+}
+
+// scanTargets returns a pointer to scan into for each verb in the format.
+func scanTargets(format string) ([]interface{}, error) {
+	var targets []interface{}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		// Skips the flags and the width of the verb.
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789", format[i]) >= 0 {
+			i++
+		}
+		if i == len(format) {
+			return nil, fmt.Errorf("format \"%s\" ends with an incomplete verb", format)
+		}
+
+		switch verb := format[i]; verb {
+		case '%':
+		case 's', 'q', 'v', 'x', 'X':
+			targets = append(targets, new(string))
+		case 'd', 'b', 'o':
+			targets = append(targets, new(int64))
+		case 'f', 'F', 'g', 'G', 'e', 'E':
+			targets = append(targets, new(float64))
+		case 't':
+			targets = append(targets, new(bool))
+		default:
+			return nil, fmt.Errorf("format \"%s\" has unsupported verb %%%c", format, verb)
+		}
+	}
+	return targets, nil
+}
+
+// assertScan ensures that each format matches a line of the output, storing
+// the fields of the first line that matches.
+func assertScan(out string, formats []ScanFormat, storage teststorage.Storage) error {
+	var errs []error
+	for _, f := range formats {
+		targets, err := scanTargets(f.Format)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(targets) != len(f.Into) {
+			errs = append(errs, fmt.Errorf("format \"%s\" has %d fields, but %d storage keys are set",
+				f.Format, len(targets), len(f.Into),
+			))
+			continue
+		}
+
+		var matched bool
+		for _, line := range strings.Split(out, "\n") {
+			if n, err := fmt.Sscanf(line, f.Format, targets...); err == nil && n == len(targets) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, fmt.Errorf("no line matches format \"%s\" in standard output: \"%s\"", f.Format, out))
+			continue
+		}
+
+		for i, key := range f.Into {
+			// Dereferences the targets so they're stored as their values.
+			var value interface{}
+			switch v := targets[i].(type) {
+			case *string:
+				value = *v
+			case *int64:
+				value = *v
+			case *float64:
+				value = *v
+			case *bool:
+				value = *v
+			}
+			storage.Set(key, fmt.Sprint(value))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must scan formats", errors.Join(errs...))
+	}
+	return nil
+}
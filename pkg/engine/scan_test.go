@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func Test_assertScan(t *testing.T) {
+	const out = "Creating cluster\nCreated cluster a1b2c3 in us-east-1\nNodes: 3, healthy: true, usage: 0.75\n"
+	tests := []struct {
+		name    string
+		formats []ScanFormat
+		want    map[string]string
+		err     string
+	}{
+		{
+			name: "stores the fields of the matching line",
+			formats: []ScanFormat{
+				{Format: "Created cluster %s in %s", Into: []string{"id", "region"}},
+				{Format: "Nodes: %d, healthy: %t, usage: %f", Into: []string{"nodes", "healthy", "usage"}},
+			},
+			want: map[string]string{
+				"id": "a1b2c3", "region": "us-east-1",
+				"nodes": "3", "healthy": "true", "usage": "0.75",
+			},
+		},
+		{
+			name:    "fails when no line matches",
+			formats: []ScanFormat{{Format: "Deleted cluster %s", Into: []string{"id"}}},
+			err:     "no line matches format \"Deleted cluster %s\"",
+		},
+		{
+			name:    "fails when the keys don't match the fields",
+			formats: []ScanFormat{{Format: "Created cluster %s in %s", Into: []string{"id"}}},
+			err:     "format \"Created cluster %s in %s\" has 2 fields, but 1 storage keys are set",
+		},
+		{
+			name:    "fails on an unsupported verb",
+			formats: []ScanFormat{{Format: "Created cluster %c", Into: []string{"id"}}},
+			err:     "format \"Created cluster %c\" has unsupported verb %c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := teststorage.NewSafeMap()
+			err := assertScan(out, tt.formats, storage)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("assertScan() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]string, len(tt.want))
+			for key := range tt.want {
+				got[key], _ = storage.Get(key)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assertScan() stored = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
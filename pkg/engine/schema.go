@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// inlineSchemaURL is the resource name used to compile inline schemas.
+const inlineSchemaURL = "inline.schema.json"
+
+// compileSchema compiles a JSON schema which is either inline, when it starts
+// with "{", or read from the file in the schema path.
+func compileSchema(schema string) (*jsonschema.Schema, error) {
+	if !strings.HasPrefix(strings.TrimSpace(schema), "{") {
+		return jsonschema.Compile(schema)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(inlineSchemaURL, strings.NewReader(schema)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(inlineSchemaURL)
+}
+
+func assertJSONSchema(out string, schema string) error {
+	if schema == "" {
+		return nil
+	}
+
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return NewPrefixedError("must match JSON schema",
+			fmt.Errorf("invalid JSON schema: %w", err),
+		)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		return NewPrefixedError("must match JSON schema",
+			fmt.Errorf("standard output is not valid JSON: %w: \"%s\"", err, out),
+		)
+	}
+
+	err = compiled.Validate(doc)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		if err != nil {
+			return NewPrefixedError("must match JSON schema", err)
+		}
+		return nil
+	}
+
+	// Only the errors of the failing keywords are reported, skipping the
+	// summaries of their parents.
+	var errs []error
+	for _, e := range validationErr.BasicOutput().Errors {
+		if e.Error == "" || len(validationErr.Causes) > 0 && e.KeywordLocation == "" {
+			continue
+		}
+		location := e.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		errs = append(errs, fmt.Errorf("%s: %s", location, e.Error))
+	}
+	if len(errs) == 0 {
+		errs = append(errs, validationErr)
+	}
+	return NewPrefixedError("must match JSON schema", errors.Join(errs...))
+}
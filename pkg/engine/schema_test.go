@@ -0,0 +1,112 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_assertJSONSchema(t *testing.T) {
+	const schema = `{
+		"type": "object",
+		"required": ["id", "status"],
+		"properties": {
+			"id": {"type": "string"},
+			"status": {"enum": ["ok", "failed"]},
+			"size": {"type": "integer", "minimum": 0}
+		}
+	}`
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte(schema), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		out    string
+		schema string
+		err    []string
+	}{
+		{
+			name:   "no schema",
+			out:    "not JSON",
+			schema: "",
+		},
+		{
+			name:   "valid against an inline schema",
+			out:    `{"id": "a1", "status": "ok", "size": 3}`,
+			schema: schema,
+		},
+		{
+			name:   "valid against a schema file",
+			out:    `{"id": "a1", "status": "failed"}`,
+			schema: path,
+		},
+		{
+			name:   "reports each validation error",
+			out:    `{"id": 1, "size": -1}`,
+			schema: path,
+			err: []string{
+				"must match JSON schema",
+				"/: missing properties: 'status'",
+				"/id: expected string, but got number",
+				"/size: must be >= 0 but found -1",
+			},
+		},
+		{
+			name:   "fails when the output is not JSON",
+			out:    "status: ok",
+			schema: schema,
+			err:    []string{"standard output is not valid JSON"},
+		},
+		{
+			name:   "fails when the schema is invalid",
+			out:    `{}`,
+			schema: `{"type": 1}`,
+			err:    []string{"invalid JSON schema"},
+		},
+		{
+			name:   "fails when the schema file doesn't exist",
+			out:    `{}`,
+			schema: filepath.Join(t.TempDir(), "missing.json"),
+			err:    []string{"invalid JSON schema"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertJSONSchema(tt.out, tt.schema)
+			if len(tt.err) == 0 {
+				if err != nil {
+					t.Errorf("assertJSONSchema() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("assertJSONSchema() error = nil, want %v", tt.err)
+			}
+			for _, want := range tt.err {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("assertJSONSchema() error = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+// stableStorage is a view of the storage which tracks the values written on
+// each attempt of a test, recording a conflict when an attempt writes a
+// different value in a key that a previous attempt has set.
+type stableStorage struct {
+	teststorage.Storage
+
+	mu        sync.Mutex
+	attempt   int
+	written   map[string]storedValue
+	conflicts []error
+}
+
+// storedValue is a value written in the storage and the attempt it was
+// written on.
+type storedValue struct {
+	value   string
+	attempt int
+}
+
+func newStableStorage(s teststorage.Storage) *stableStorage {
+	return &stableStorage{Storage: s, written: make(map[string]storedValue)}
+}
+
+// nextAttempt marks the start of a new attempt. Writes within an attempt can
+// overwrite each other.
+func (s *stableStorage) nextAttempt() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempt++
+}
+
+// Set persists the value into the key, recording a conflict when a previous
+// attempt stored a different value.
+func (s *stableStorage) Set(k, v string) {
+	s.mu.Lock()
+	if prev, ok := s.written[k]; ok && prev.attempt < s.attempt && prev.value != v {
+		s.conflicts = append(s.conflicts, fmt.Errorf(
+			"attempt %d stored \"%s\" in key \"%s\", but attempt %d stored \"%s\"",
+			s.attempt, v, k, prev.attempt, prev.value,
+		))
+	}
+	s.written[k] = storedValue{value: v, attempt: s.attempt}
+	s.mu.Unlock()
+
+	s.Storage.Set(k, v)
+}
+
+// err returns the conflicts recorded so far, which fail every later attempt.
+func (s *stableStorage) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.conflicts) > 0 {
+		return NewPrefixedError("unstable storage writes across retries", errors.Join(s.conflicts...))
+	}
+	return nil
+}
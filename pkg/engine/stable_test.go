@@ -0,0 +1,79 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func Test_stableStorage(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts [][][2]string
+		err      string
+	}{
+		{
+			name: "same values across attempts",
+			attempts: [][][2]string{
+				{{"id", "a1"}},
+				{{"id", "a1"}, {"status", "ok"}},
+			},
+		},
+		{
+			name: "overwrites within an attempt",
+			attempts: [][][2]string{
+				{{"id", "a1"}, {"id", "a2"}},
+				{{"id", "a2"}},
+			},
+		},
+		{
+			name: "different value on a retry",
+			attempts: [][][2]string{
+				{{"id", "a1"}},
+				{{"id", "b2"}},
+				{{"id", "b2"}},
+			},
+			err: "attempt 2 stored \"b2\" in key \"id\", but attempt 1 stored \"a1\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStableStorage(teststorage.NewSafeMap())
+			for _, writes := range tt.attempts {
+				s.nextAttempt()
+				for _, w := range writes {
+					s.Set(w[0], w[1])
+				}
+			}
+
+			err := s.err()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("err() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
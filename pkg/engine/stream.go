@@ -0,0 +1,123 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Stream identifies one of the output streams of a command.
+type Stream string
+
+const (
+	// Stdout is the standard output.
+	Stdout Stream = "stdout"
+	// Stderr is the standard error.
+	Stderr Stream = "stderr"
+	// Combined is the output of both streams in the order it was read.
+	Combined Stream = "combined"
+)
+
+// String returns the descriptive name of the stream, as used in the failure
+// messages.
+func (s Stream) String() string {
+	switch s {
+	case Stderr:
+		return "standard error"
+	case Combined:
+		return "combined output"
+	}
+	return "standard output"
+}
+
+// needsCombined returns true when any of the streams is Combined.
+func needsCombined(streams map[string]Stream) bool {
+	for _, s := range streams {
+		if s == Combined {
+			return true
+		}
+	}
+	return false
+}
+
+// other returns the opposite stream.
+func (s Stream) other() Stream {
+	if s == Stderr {
+		return Stdout
+	}
+	return Stderr
+}
+
+// CrossStreamMatch captures a value from one stream and ensures that it's also
+// found in the other stream, such as a request ID logged on the standard error
+// which must be part of the standard output.
+type CrossStreamMatch struct {
+	// Regex pattern which captures the value in its first group, or in the
+	// whole match when the pattern has no groups.
+	Pattern string
+
+	// Stream the value is captured from, the value must be found in the
+	// other one. Defaults to Stderr.
+	From Stream
+}
+
+func assertCrossStream(stdout, stderr string, matches []CrossStreamMatch) error {
+	outputs := map[Stream]string{Stdout: stdout, Stderr: stderr}
+
+	var errs []error
+	for _, m := range matches {
+		from := m.From
+		if from == "" {
+			from = Stderr
+		}
+		if _, ok := outputs[from]; !ok {
+			errs = append(errs, fmt.Errorf("unknown stream \"%s\" for pattern \"%s\"", string(from), m.Pattern))
+			continue
+		}
+
+		re, err := regexp.Compile(m.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cross stream pattern \"%s\" did not compile: %w", m.Pattern, err))
+			continue
+		}
+
+		match := re.FindStringSubmatch(outputs[from])
+		if match == nil {
+			errs = append(errs, fmt.Errorf(
+				"couldn't match cross stream pattern \"%s\" to %s: \"%s\"", m.Pattern, from, outputs[from],
+			))
+			continue
+		}
+
+		value, to := firstGroup(match), from.other()
+		if !strings.Contains(outputs[to], value) {
+			errs = append(errs, fmt.Errorf(
+				"value \"%s\" captured from %s by pattern \"%s\" wasn't found in %s: \"%s\"",
+				value, from, m.Pattern, to, outputs[to],
+			))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must match across streams", errors.Join(errs...))
+	}
+	return nil
+}
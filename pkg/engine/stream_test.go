@@ -0,0 +1,125 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func Test_assertCrossStream(t *testing.T) {
+	const (
+		stdout = `{"request_id": "a1b2c3", "status": "ok"}` + "\n"
+		stderr = "sending request a1b2c3\n"
+	)
+	tests := []struct {
+		name    string
+		matches []CrossStreamMatch
+		err     string
+	}{
+		{
+			name:    "Finds the value captured from the standard error in the standard output",
+			matches: []CrossStreamMatch{{Pattern: `request ([a-z0-9]+)`}},
+		},
+		{
+			name:    "Finds the value captured from the standard output in the standard error",
+			matches: []CrossStreamMatch{{Pattern: `"request_id": "([a-z0-9]+)"`, From: Stdout}},
+		},
+		{
+			name:    "Fails when the captured value isn't in the other stream",
+			matches: []CrossStreamMatch{{Pattern: `"status": "([a-z]+)"`, From: Stdout}},
+			err:     "value \"ok\" captured from standard output by pattern \"\"status\": \"([a-z]+)\"\" wasn't found in standard error",
+		},
+		{
+			name:    "Fails when the pattern doesn't match",
+			matches: []CrossStreamMatch{{Pattern: `trace ([a-z0-9]+)`}},
+			err:     "couldn't match cross stream pattern \"trace ([a-z0-9]+)\" to standard error",
+		},
+		{
+			name:    "Fails on unknown streams",
+			matches: []CrossStreamMatch{{Pattern: `request`, From: "stdin"}},
+			err:     "unknown stream \"stdin\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertCrossStream(stdout, stderr, tt.matches)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertCrossStream() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertCrossStream() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTestCallback_RunStreams(t *testing.T) {
+	store := func(out []byte, key string, storage teststorage.Storage) error {
+		storage.Set(key, string(out))
+		return nil
+	}
+	outputs := map[Stream][]byte{
+		Stdout:   []byte("out"),
+		Stderr:   []byte("err"),
+		Combined: []byte("outerr"),
+	}
+	tests := []struct {
+		name    string
+		streams map[string]Stream
+		want    map[string]string
+		err     string
+	}{
+		{
+			name: "defaults to the standard output",
+			want: map[string]string{"a": "out", "b": "out"},
+		},
+		{
+			name:    "passes the selected streams",
+			streams: map[string]Stream{"a": Stderr, "b": Combined},
+			want:    map[string]string{"a": "err", "b": "outerr"},
+		},
+		{
+			name:    "fails on an unsupported stream",
+			streams: map[string]Stream{"a": "stdin"},
+			want:    map[string]string{"b": "out"},
+			err:     "unsupported stream \"stdin\" for key \"a\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := teststorage.NewSafeMap()
+			tc := TestCallback{"a": store, "b": store}
+			err := tc.RunStreams(outputs, tt.streams, storage)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("RunStreams() error = %v, want %v", err, tt.err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range tt.want {
+				if got, _ := storage.Get(key); got != want {
+					t.Errorf("RunStreams() stored %s = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
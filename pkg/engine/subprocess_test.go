@@ -0,0 +1,43 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// subprocessEnv is set when the test binary runs a test in a subprocess.
+const subprocessEnv = "TESTCLI_TEST_SUBPROCESS"
+
+// inSubprocess returns true when the test runs in a subprocess started by
+// runInSubprocess, where it can fail without failing the suite.
+func inSubprocess() bool { return os.Getenv(subprocessEnv) != "" }
+
+// runInSubprocess runs the test in a verbose subprocess of the test binary,
+// returning its output, so the logs of tests which are expected to fail can
+// be checked.
+func runInSubprocess(t *testing.T) string {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^"+t.Name()+"$", "-test.v", "-test.count=1")
+	cmd.Env = append(os.Environ(), subprocessEnv+"=1")
+	// The subprocess exits with an error when its test fails.
+	out, _ := cmd.CombinedOutput()
+	return string(out)
+}
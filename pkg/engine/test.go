@@ -19,11 +19,23 @@ package engine
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os/exec"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/elastic/testcli/pkg/engine/teststorage"
 )
@@ -45,23 +57,171 @@ type Test struct {
 	// can be found within the project directory boundaries.
 	FindBinary bool
 
+	// Directory the reverse walk of FindBinary starts from, such as the "bin"
+	// directory of the project. Defaults to the current directory.
+	SearchRoot string
+
+	// When set, the reverse walk of FindBinary fails the test once it takes
+	// longer than the duration, instead of hanging on huge repositories.
+	FindBinaryTimeout time.Duration
+
 	// Arguments to pass to the binary.
 	Args Args
 
+	// Directory the command is run in. Defaults to the current directory.
+	WorkingDir string
+
+	// Environment variables which must be set and not empty for the test to
+	// run, otherwise the test is skipped.
+	RequireEnv []string
+
+	// When set, the file is written to a temporary directory before the
+	// command runs, replacing the ConfigFilePlaceholder in the arguments with
+	// its path.
+	ConfigFile *ConfigFile
+
+	// When set, a scratch directory is created before the command runs and
+	// removed once the test finishes, replacing the TempDirPlaceholder in the
+	// arguments, the WorkingDir, the PreCommands, the Env values and the
+	// FileAssertions paths with its path.
+	TempDir bool
+
+	// When set, the TempDir of a failed test isn't removed, and its path is
+	// logged so the files left by the command can be inspected.
+	KeepTempDirOnFailure bool
+
+	// Environment variables which are set for the command, overriding the
+	// inherited ones.
+	Env map[string]string
+
+	// Inherited environment variables which are removed from the command's
+	// environment. The Env variables are set regardless.
+	UnsetEnv []string
+
+	// Commands which are run in order, in the WorkingDir, before the binary.
+	// Each command is the binary followed by its arguments. The test fails
+	// when any of them errors.
+	PreCommands [][]string
+
+	// Name of the encoding of the command's output (e.g. "latin1", "utf-16le"),
+	// as defined in https://encoding.spec.whatwg.org. When set, the output is
+	// decoded into UTF-8 before it's asserted or passed to the callbacks.
+	OutputEncoding string
+
 	// the following list of strings must be found in the specified
 	// channels, stdout, stderr.
 	Assert Assertions
 
+	// Files which the command must have created or left untouched, checked
+	// after the command is run.
+	FileAssertions []FileAssertion
+
+	// When set, the command is run a second time once the test passes, and
+	// the standard output of both runs must be identical.
+	AssertIdempotent bool
+
+	// Replacements applied to the standard output of both runs before they
+	// are compared by AssertIdempotent, masking the intentional variability
+	// such as timestamps or generated IDs.
+	Normalize []Replacement
+
+	// Number of times the command is run again once the test passes. The
+	// size of the standard output of each run can't grow beyond the
+	// RepeatGrowthTolerance from the first run, catching accumulation bugs
+	// which only show up when the command is repeated.
+	Repeat int
+
+	// Fraction of the first run's output size which the repeated runs can
+	// grow by, such as 0.1 for 10%.
+	RepeatGrowthTolerance float64
+
+	// Environment variables which the command is run again with once the
+	// test passes, one run per map, applied on top of Env. The standard
+	// output of each run must be identical to the test's once normalized
+	// with Normalize, catching hidden dependencies on the environment.
+	EnvVariants []map[string]string
+
+	// Comparisons of the output size with the runs of the command with other
+	// arguments, which are run once the test passes. Useful to test that
+	// flags such as --quiet and --verbose change the amount of output.
+	CompareOutputSize []OutputSizeComparison
+
 	// callbacks to be run after the test is finished, the stdout output
 	// is passed as the first argument and the key is used, see decode...
 	// functions for callback examples
 	Callbacks TestCallback
 
+	// Selects the stream passed to the callback stored with the same key,
+	// either Stdout, Stderr or Combined. Callbacks without an entry are passed
+	// the standard output.
+	CallbackStreams map[string]Stream
+
+	// When set, it's called with each line of the standard output, without
+	// its line ending, as the command writes it. Unlike the callbacks, it's
+	// called while the command is running, in the order of the lines.
+	OnOutput func(line string)
+
+	// When set, receive a copy of the standard output and error as the
+	// command writes them, such as os.Stdout to tail a long test. They don't
+	// affect the output captured for the assertions. The writes are
+	// synchronized, so the same writer can be used for both.
+	OutputWriter io.Writer
+	ErrorWriter  io.Writer
+
+	// When set, the raw standard output is stored under this storage key after
+	// the test is run.
+	StoreStdoutKey string
+
+	// When set, the raw standard error is stored under this storage key after
+	// the test is run.
+	StoreStderrKey string
+
 	// optionally set how much time the test should wait before run
 	WaitBeforeRun time.Duration
 
+	// When set, the function is polled until it returns nil before running the
+	// command, which gates the test on a readiness condition rather than on a
+	// fixed wait. The polling backs off from 500ms up to 5s between the calls. The context is done once the ReadyTimeout is exceeded, so a
+	// check which blocks must return by then. See CommandReady for a command
+	// based check.
+	WaitForReady func(ctx context.Context) error
+
+	// Maximum time to wait for WaitForReady to return nil before failing the
+	// test. Defaults to 30s.
+	ReadyTimeout time.Duration
+
 	// If set, the test will be run in parallel instead of sequentially.
 	Parallel bool
+
+	// Number of times the command is run again when its assertions or its
+	// callbacks fail. Only the last attempt's result is reported.
+	Retries int
+
+	// When set, failed attempts are only retried when their standard error
+	// contains any of these values, otherwise the test fails right away.
+	RetryOnStderr []string
+
+	// Time to wait before the first retry, which doubles on each retry.
+	RetryBackoff time.Duration
+
+	// When set, caps the time to wait before each retry.
+	RetryMaxBackoff time.Duration
+
+	// Fraction between 0 and 1 of each delay which is randomly subtracted
+	// from it, so parallel tests retrying against the same backend don't
+	// retry at the same time.
+	RetryJitter float64
+
+	// When set, a retry which stores a different value in a storage key set
+	// by a previous attempt fails the test, surfacing nondeterministic output
+	// which the retries would otherwise hide.
+	StableRetryWrites bool
+
+	// Names of the tests which must finish before this test runs. The test is
+	// skipped when any of them fails or is skipped. The tests which other tests
+	// depend on run sequentially, ordered by their dependencies, before the
+	// parallel tests start. Cycles fail the whole suite.
+	DependsOn []string
 }
 
 // Args represent the test arguments.
@@ -75,11 +235,24 @@ type Args struct {
 	Config []string
 
 	// Uses the strings as keys to load the stored value from `teststorage.Storage`
-	// the parameter is ignored if not found in the result map, and passed as the key
+	// the parameter is ignored if not found in the result map, and passed as the key.
+	// A default value can be specified as "key|=fallback", which is used when the
+	// key is not found in the storage, otherwise a missing key fails the test.
+	// The values of several keys are joined into a single argument with the
+	// "concat:sep=-:keyA,keyB" syntax, where "sep=-:" sets the optional separator.
+	// Keys prefixed with "-" or "strip_" are appended as literal arguments,
+	// prefer TrailingArgs for the literal arguments after the dynamic ones.
 	DynamicArgs []string
 
+	// Arguments appended verbatim after the DynamicArgs.
+	TrailingArgs []string
+
 	// list of commands to be run when an interactive session is open
 	Interactive []string
+
+	// Pause between writing each of the Interactive lines, for programs
+	// which aren't ready to read the next line as soon as they read one.
+	InteractiveDelay time.Duration
 }
 
 // Assertions defines a series of Must and MustNot assertions after a test is
@@ -89,6 +262,10 @@ type Assertions struct {
 	// execution.
 	WantErr bool
 
+	// When set along with WantErr, the failing command must also write to the
+	// standard error, catching commands which fail silently.
+	WantErrWithStderr bool
+
 	// CanError causes the test not to fail in case the command returns an error.
 	// This is useful for commands which can return an error depending on external
 	// factors, useful when the output is still asserted but an error might be returned.
@@ -98,11 +275,87 @@ type Assertions struct {
 	// will not fail, if there's a partial match of any of the messages.
 	CanErrorWithMessage []string
 
+	// CanFailToStart allows a binary which couldn't be found or executed to
+	// satisfy WantErr, CanError and CanErrorWithMessage. Otherwise, a binary
+	// which never ran always causes the test to fail.
+	CanFailToStart bool
+
+	// When set, the exit code must be within the range, which replaces the
+	// WantErr, CanError and CanErrorWithMessage checks on the command error.
+	ExitCodeRange *ExitCodeRange
+
+	// Conditions on the exit code of the command which depend on the exit
+	// codes of the tests which ran before it. Since the exit code depends on
+	// the other tests, they're usually combined with CanError.
+	ExitCodeConditions []ExitCodeCondition
+
+	// When set, all the interactive lines must be written before the command
+	// exits, surfacing commands which prompt fewer times than expected.
+	AllInputConsumed bool
+
+	// Interactive lines which must not be found in the output once they are
+	// written, such as a password typed on a prompt which mustn't be echoed.
+	// The failures refer to the lines by their index, not their value.
+	MustNotEchoInput []string
+
+	// When set, the command must fail with the expected error within the
+	// duration, such as when its own timeout is exceeded. The command is
+	// killed if it's still running once the duration has elapsed.
+	Timeout *TimeoutBehavior
+
 	// Must ensures that the defined assertions are found.
 	Must Assertion
 
 	// Not ensures that the defined assertions are not found.
 	Not Assertion
+
+	// Should defines advisory assertions, which are logged as warnings when
+	// they aren't found instead of failing the test.
+	Should Assertion
+
+	// Comparator is a custom function to validate the output. The returned
+	// error is reported along with the rest of the failed assertions.
+	Comparator func(stdout, stderr string) error
+}
+
+// ExitCodeRange defines the inclusive range the exit code must be within.
+type ExitCodeRange struct {
+	Min int
+	Max int
+}
+
+// TimeoutBehavior defines how a command which respects its own timeout must
+// fail.
+type TimeoutBehavior struct {
+	// Duration within which the command must return.
+	Within time.Duration
+
+	// Partial message which the standard error must contain.
+	ErrContains string
+}
+
+// assertTimeout ensures that the command failed with the expected error within
+// the duration of the timeout behavior.
+func assertTimeout(tb *TimeoutBehavior, duration time.Duration, err error, stderr string) error {
+	if tb == nil {
+		return nil
+	}
+
+	var errs []error
+	if duration > tb.Within {
+		errs = append(errs, fmt.Errorf("command took %s, want it to return within %s", duration, tb.Within))
+	}
+	if err == nil {
+		errs = append(errs, errors.New("command succeeded, want it to fail"))
+	}
+	if !strings.Contains(stderr, tb.ErrContains) {
+		errs = append(errs, fmt.Errorf("standard error doesn't contain \"%s\": \"%s\"", tb.ErrContains, stderr))
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("timeout", errors.Join(errs...))
+	}
+	return nil
 }
 
 // Assertion represent the test assertions after the test has run.
@@ -110,18 +363,255 @@ type Assertion struct {
 	// Asserts the Output
 	Output []string
 
+	// Computes additional Output entries when the assertion is evaluated,
+	// such as values derived from the storage or the current date. An error
+	// fails the assertion.
+	ExpectedFunc func(storage teststorage.Storage) ([]string, error)
+
+	// Path of a file with additional Output entries, one per line, which
+	// keeps long lists of expected values out of the tests. Blank lines and
+	// lines starting with "#" are ignored. A file which can't be read fails
+	// the assertion.
+	MustContainFile string
+
 	// Asserts the errors
 	Errors []string
 
-	// Asserts dynamically stored values (Key-based).
+	// Limits the number of standard error lines matching a pattern, such as
+	// the deprecation warnings which are being cleaned up.
+	MaxErrorMatches []ErrorMatchLimit
+
+	// Regex pattern which filters the standard error lines that Errors are
+	// asserted against, ignoring the rest of them. The failure messages still
+	// include the whole standard error.
+	ErrorLineFilter string
+
+	// Asserts dynamically stored values (Key-based). When the key is not
+	// found in the storage, the key itself is asserted.
 	Dynamic []string
 
-	// When set to true, it ensures that all the items in Output and Errors are
-	// are found.
+	// Asserts that the timestamps captured from the standard output are
+	// recent.
+	RecentTimestamps []RecentTimestamp
+
+	// Names of the tests which must have finished in this order, such as
+	// parallel tests which this test depends on.
+	CompletedInOrder []string
+
+	// Storage key of a baseline output, such as one stored by a previous
+	// test with StoreStdoutKey, which the standard output must differ from.
+	DifferentFrom string
+
+	// Storage key of a baseline output whose lines must all be found in the
+	// lines of the standard output, such as a list which only grows.
+	SupersetOf string
+
+	// Asserts the text around a specific occurrence of an anchor in the
+	// standard output.
+	Occurrences []OccurrenceContext
+
+	// Each format must match a line of the standard output, storing the
+	// parsed fields under the storage keys.
+	Scan []ScanFormat
+
+	// When set to true, the standard output must be equal to each of the
+	// items in Output.
 	Strict bool
 
+	// When set to true, the standard error must be equal to each of the items
+	// in Errors, ignoring its surrounding whitespace.
+	StrictErrors bool
+
 	// Regex Patterns to match.
 	Pattern []string
+
+	// Maps regex patterns to the minimum number of times they must match the
+	// standard output, or to the exact number of times when ExactPatternCounts
+	// is set.
+	PatternCounts map[string]int
+
+	// When set to true, the PatternCounts are the exact number of matches.
+	ExactPatternCounts bool
+
+	// Numbers of distinct values which regex patterns must capture from the
+	// standard output.
+	DistinctCounts []DistinctCount
+
+	// Maps a zero-based line index of the standard output to the value that
+	// the line must contain, or be equal to when Strict is set.
+	LineAssertions map[int]string
+
+	// Ordered lines which the standard output lines must be equal to,
+	// reporting the first line which differs.
+	Lines []string
+
+	// Lines which the non-empty standard output lines must be equal to as a
+	// set, regardless of their order and repetitions. The lines which are
+	// missing and the ones which are unexpected are reported.
+	LineSet []string
+
+	// Numbers extracted from the standard output which must be within a
+	// tolerance of the expected value.
+	NumericAssertions []NumericAssertion
+
+	// Ensures that the numbers captured from the standard output lines are
+	// increasing.
+	Monotonic MonotonicAssertion
+
+	// When set, the standard output lines must be sorted in ascending or
+	// descending order, compared as strings.
+	SortedAscending  bool
+	SortedDescending bool
+
+	// Regex pattern which selects the lines checked by SortedAscending and
+	// SortedDescending, such as the rows of a table without its header.
+	SortedLineFilter string
+
+	// Values which must approximately be found in the standard output.
+	FuzzyMatch []FuzzyMatch
+
+	// Values captured from one stream which must also be found in the other.
+	CrossStreamMatch []CrossStreamMatch
+
+	// When set, the trimmed standard output is base64 decoded before the
+	// rest of the assertions are evaluated against it.
+	Base64Decode bool
+
+	// When set, the standard output is gzip decompressed before the rest of
+	// the assertions are evaluated against it, after Base64Decode.
+	GunzipOutput bool
+
+	// Hex encoded SHA-256 digest which the standard output must have, pinning
+	// large deterministic outputs. On a mismatch, the computed digest is
+	// reported so it can be copied into the assertion.
+	OutputSHA256 string
+
+	// Replacements applied to the standard output before its digest is
+	// computed, such as replacing timestamps with a fixed value.
+	OutputSHA256Normalize []Replacement
+
+	// Regex patterns delimiting the section of the standard output which the
+	// rest of the assertions are evaluated against, excluding the delimiters.
+	// When only SectionStart is set, the section ends with the output, and
+	// when only SectionEnd is set, it starts with the output.
+	SectionStart string
+	SectionEnd   string
+
+	// Regex pattern of a prefix which is removed from each line of the
+	// standard output before the rest of the assertions, such as the line
+	// numbers or timestamps of a log. It's matched at the start of the line.
+	StripLinePrefix string
+
+	// When set, the repeated lines of the standard output are removed before
+	// the rest of the assertions, after StripLinePrefix. Only the adjacent
+	// duplicates are removed, unless DedupeGlobal is set, which removes every
+	// line that was already found.
+	DedupeLines  bool
+	DedupeGlobal bool
+
+	// Hex encoded fragments which must be found in the hex encoding of the
+	// standard output, such as a magic header in binary output. Whitespace
+	// and case are ignored, e.g. "89 50 4E 47".
+	HexContains []string
+
+	// When set, the standard output must have exactly this number of bytes.
+	OutputByteLen *int
+
+	// When set, the first HTTP status found in the standard output, or in the
+	// standard error when there's none, must be equal to it. The status is
+	// the code followed by its reason phrase, such as "404 Not Found".
+	HTTPStatus *int
+
+	// When set, the standard output must be empty or only have whitespace.
+	EmptyOutput bool
+
+	// When set, the standard error must be empty or only have whitespace.
+	EmptyErrors bool
+
+	// When set, the standard output must not have control characters other
+	// than tabs and newlines, which usually point to a serialization bug.
+	NoControlChars bool
+
+	// When set, the standard output must be valid UTF-8, which is checked on
+	// the output as it's captured, before Base64Decode and the rest of the
+	// transformations. The offset of the first invalid sequence is reported.
+	MustBeValidUTF8 bool
+
+	// Maps JSON paths (e.g. "items", "hrefs[\"api/v1\"]") to the number of
+	// elements the array found in the path of the JSON standard output must
+	// have. See the callbacks package for the path syntax.
+	JSONArrayLen map[string]int
+
+	// Expected JSON document which the JSON standard output must be
+	// structurally equal to, regardless of its formatting and key order.
+	JSON string
+
+	// JSON paths of the volatile fields, such as timestamps or generated IDs,
+	// which are ignored when comparing the output to the JSON document.
+	JSONIgnoreFields []string
+
+	// When set, the standard output must only be a JSON document, keeping
+	// the machine readable output apart from the logs on the standard error.
+	StdoutOnlyJSON bool
+
+	// When set, the standard error must only be a JSON document, regardless
+	// of the standard output.
+	StderrOnlyJSON bool
+
+	// JSON schema, either inline or the path to the schema file, which the
+	// standard output must be valid against.
+	JSONSchema string
+
+	// Describes the intent of the assertion, such as "the cluster must have
+	// started". When set, it's prepended to the assertion failures.
+	Message string
+}
+
+// ErrorMatchLimit defines the maximum number of standard error lines which can
+// match a pattern.
+type ErrorMatchLimit struct {
+	// Regex pattern which the lines are matched against.
+	Pattern string
+
+	// Maximum number of lines which can match the pattern.
+	Max int
+}
+
+// FuzzyMatch defines a value which is found when any part of the output is
+// within a maximum Levenshtein distance of it.
+type FuzzyMatch struct {
+	// The value to find.
+	Want string
+
+	// Maximum number of single character insertions, deletions or
+	// substitutions between Want and the best matching part of the output.
+	MaxDistance int
+}
+
+// NumericAssertion extracts a number from the output and compares it with an
+// expected value.
+type NumericAssertion struct {
+	// Regex pattern which captures the number in its first group, or in the
+	// whole match when the pattern has no groups.
+	Pattern string
+
+	// The value that the captured number is expected to be.
+	Expected float64
+
+	// Maximum absolute difference between the captured and expected values.
+	Tolerance float64
+}
+
+// MonotonicAssertion ensures that the numbers captured from each of the lines
+// matching a pattern are increasing.
+type MonotonicAssertion struct {
+	// Regex pattern which captures the number in its first group, or in the
+	// whole match when the pattern has no groups. Lines that don't match the
+	// pattern are ignored.
+	Pattern string
+
+	// When set, consecutive values are allowed to be equal.
+	AllowEqual bool
 }
 
 // Callback is a function which receives the output in the form of []byte and
@@ -136,8 +626,23 @@ type TestCallback map[string]Callback
 // Run calls each stored callback and stores the output of the command on the
 // passed storage via the prefixed key in the callback map.
 func (tc TestCallback) Run(out []byte, storage teststorage.Storage) error {
+	return tc.RunStreams(map[Stream][]byte{Stdout: out}, nil, storage)
+}
+
+// RunStreams calls each stored callback with the output of the stream selected
+// for its key in streams, defaulting to the standard output.
+func (tc TestCallback) RunStreams(outputs map[Stream][]byte, streams map[string]Stream, storage teststorage.Storage) error {
 	var errs []error
 	for key, callback := range tc {
+		stream := Stdout
+		if s, ok := streams[key]; ok {
+			stream = s
+		}
+		out, ok := outputs[stream]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unsupported stream \"%s\" for key \"%s\"", string(stream), key))
+			continue
+		}
 		if err := callback(out, key, storage); err != nil {
 			errs = append(errs, err)
 		}
@@ -157,21 +662,55 @@ func NewTestCallback(s string, t Callback) TestCallback {
 
 // Ensure verifies that the assertions match, otherwise it throws an error via t.Error
 func (a Assertions) Ensure(stdout, stderr *bytes.Buffer, err error, storage teststorage.Storage, args string) error {
+	return a.ensure(stdout, stderr, err, storage, args, time.Now)
+}
+
+// ensure is Ensure with the function which returns the current time the
+// RecentTimestamps are compared to.
+func (a Assertions) ensure(stdout, stderr *bytes.Buffer, err error, storage teststorage.Storage, args string, now func() time.Time) error {
 	// Checks standard for unexpected errors when running the command
 	// if err is true when WantErr is false, it will error out
 	// The same applies when WantErr is true, but err is false.
 	var stderrString = stderr.String()
-	if (err != nil) != a.WantErr && !a.CanError && len(a.CanErrorWithMessage) == 0 {
-		return fmt.Errorf(
-			"command: \"%s\"\nerror = %v, wantErr = %v, stderr = %v", args, err, a.WantErr, stderrString,
-		)
+	if isStartError(err) && !a.CanFailToStart {
+		return fmt.Errorf("command: \"%s\"\nfailed to start: %v", args, err)
 	}
 
-	// If an error is returned and partially matches CanErrorWithMessage,
-	// returning nil, and skipping any further assertions.
-	for _, knownFailure := range a.CanErrorWithMessage {
-		if strings.Contains(stderrString, knownFailure) {
-			return nil
+	if r := a.ExitCodeRange; r != nil {
+		if code := exitCode(err); code < r.Min || code > r.Max {
+			return fmt.Errorf(
+				"command: \"%s\"\nexit code %d is not within [%d, %d], stderr = %v",
+				args, code, r.Min, r.Max, stderrString,
+			)
+		}
+	} else {
+		// A timeout behavior implies the command fails. A command which
+		// succeeds instead is reported by assertTimeout along with its error
+		// message, rather than twice.
+		wantErr := a.WantErr || a.Timeout != nil
+		succeededTimeout := err == nil && a.Timeout != nil && !a.WantErr
+		if (err != nil) != wantErr && !succeededTimeout && !a.CanError && len(a.CanErrorWithMessage) == 0 {
+			if err == nil {
+				return fmt.Errorf(
+					"command: \"%s\"\nexited zero but expected an error, stderr = %v", args, stderrString,
+				)
+			}
+			return fmt.Errorf(
+				"command: \"%s\"\nerror = %v, wantErr = %v, stderr = %v", args, err, wantErr, stderrString,
+			)
+		}
+		if err != nil && a.WantErr && a.WantErrWithStderr && strings.TrimSpace(stderrString) == "" {
+			return fmt.Errorf(
+				"command: \"%s\"\nerrored but produced no standard error, error = %v", args, err,
+			)
+		}
+
+		// If an error is returned and partially matches CanErrorWithMessage,
+		// returning nil, and skipping any further assertions.
+		for _, knownFailure := range a.CanErrorWithMessage {
+			if strings.Contains(stderrString, knownFailure) {
+				return nil
+			}
 		}
 	}
 
@@ -179,26 +718,23 @@ func (a Assertions) Ensure(stdout, stderr *bytes.Buffer, err error, storage test
 	// of a test case.
 	out := stdout.String()
 	var errs []error
-	if err := assertWanted(out, a.Must); err != nil {
-		errs = append(errs, err)
-	}
-
-	if err := assertPattern(out, a.Must.Pattern); err != nil {
+	if err := assertExitCodeConditions(a.ExitCodeConditions, exitCode(err), storage); err != nil {
 		errs = append(errs, err)
 	}
-
-	if err := assertErrors(stderr, a.Must.Errors); err != nil {
-		errs = append(errs, err)
-	}
-
-	if err := assertDynamic(out, a.Must.Dynamic, storage); err != nil {
-		errs = append(errs, err)
+	if err := assertMust(out, stderrString, a.Must, storage, now); err != nil {
+		errs = append(errs, withMessage(a.Must, err))
 	}
 
 	// Ensures that the mustNot Output or Error is not found
 	// in the respective outputs
-	if err := assertMustNot(out, stderrString, a.Not); err != nil {
-		errs = append(errs, err)
+	if err := assertMustNot(out, stderrString, a.Not, storage); err != nil {
+		errs = append(errs, withMessage(a.Not, err))
+	}
+
+	if a.Comparator != nil {
+		if err := a.Comparator(out, stderrString); err != nil {
+			errs = append(errs, NewPrefixedError("comparator", err))
+		}
 	}
 
 	if len(errs) > 0 {
@@ -207,8 +743,132 @@ func (a Assertions) Ensure(stdout, stderr *bytes.Buffer, err error, storage test
 	return nil
 }
 
+// Warnings evaluates the Should assertions, returning an error with the ones
+// which weren't met. Unlike Ensure, the error isn't meant to fail the test.
+func (a Assertions) Warnings(stdout, stderr *bytes.Buffer, storage teststorage.Storage) error {
+	return a.warnings(stdout, stderr, storage, time.Now)
+}
+
+// warnings is Warnings with the function which returns the current time the
+// RecentTimestamps are compared to.
+func (a Assertions) warnings(stdout, stderr *bytes.Buffer, storage teststorage.Storage, now func() time.Time) error {
+	if err := assertMust(stdout.String(), stderr.String(), a.Should, storage, now); err != nil {
+		return NewPrefixedError("should", withMessage(a.Should, err))
+	}
+	return nil
+}
+
+// withMessage prepends the assertion message to its error, when it's set.
+func withMessage(w Assertion, err error) error {
+	if w.Message == "" {
+		return err
+	}
+	return NewPrefixedError(w.Message, err)
+}
+
+// isStartError returns true when the error was caused by the binary not being
+// found or not being executable, rather than by the command's exit code.
+func isStartError(err error) bool {
+	var execErr *exec.Error
+	var pathErr *fs.PathError
+	return errors.As(err, &execErr) || errors.As(err, &pathErr)
+}
+
 // Assertions
 
+// assertMust performs all the assertions which ensure that the values defined
+// in the assertion are found.
+func assertMust(out, stderr string, w Assertion, storage teststorage.Storage, now func() time.Time) error {
+	// The output is checked as it's captured, before it's transformed.
+	validUTF8 := assertValidUTF8(out, w.MustBeValidUTF8)
+
+	if w.Base64Decode {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(out))
+		if err != nil {
+			return fmt.Errorf("standard output is not valid base64: %w: \"%s\"", err, out)
+		}
+		out = string(decoded)
+	}
+
+	if w.GunzipOutput {
+		decompressed, err := gunzip(out)
+		if err != nil {
+			return fmt.Errorf("standard output is not valid gzip: %w", err)
+		}
+		out = decompressed
+	}
+
+	if w.ExpectedFunc != nil {
+		expected, err := w.ExpectedFunc(storage)
+		if err != nil {
+			return NewPrefixedError("must compute the expected output", err)
+		}
+		// The slice is capped so the entries aren't appended to the caller's.
+		w.Output = append(w.Output[:len(w.Output):len(w.Output)], expected...)
+	}
+
+	if w.MustContainFile != "" {
+		expected, err := readExpectedFile(w.MustContainFile)
+		if err != nil {
+			return NewPrefixedError("must find", err)
+		}
+		w.Output = append(w.Output[:len(w.Output):len(w.Output)], expected...)
+	}
+
+	if w.SectionStart != "" || w.SectionEnd != "" {
+		section, err := extractSection(out, w.SectionStart, w.SectionEnd)
+		if err != nil {
+			return NewPrefixedError("must find section", err)
+		}
+		out = section
+	}
+
+	if w.StripLinePrefix != "" {
+		stripped, err := stripLinePrefix(out, w.StripLinePrefix)
+		if err != nil {
+			return NewPrefixedError("must strip line prefix", err)
+		}
+		out = stripped
+	}
+
+	if w.DedupeLines {
+		out = dedupeLines(out, w.DedupeGlobal)
+	}
+
+	return errors.Join(
+		assertWanted(out, w),
+		assertPattern(out, w.Pattern),
+		assertPatternCounts(out, w),
+		assertDistinctCounts(out, w.DistinctCounts),
+		assertLines(out, w),
+		assertOrderedLines(out, w.Lines),
+		assertLineSet(out, w.LineSet),
+		assertNumeric(out, w.NumericAssertions),
+		assertMonotonic(out, w.Monotonic),
+		assertSorted(out, w),
+		assertFuzzy(out, w.FuzzyMatch),
+		assertCrossStream(out, stderr, w.CrossStreamMatch),
+		assertHTTPStatus(out, stderr, w.HTTPStatus),
+		assertEmpty(out, stderr, w),
+		assertBinary(out, w),
+		assertNoControlChars(out, w.NoControlChars),
+		validUTF8,
+		assertJSONArrayLen(out, w.JSONArrayLen),
+		assertJSONEqual(out, w),
+		assertOnlyJSON(out, stderr, w),
+		assertJSONSchema(out, w.JSONSchema),
+		assertSHA256(out, w),
+		assertErrors(stderr, w),
+		assertMaxErrorMatches(stderr, w.MaxErrorMatches),
+		assertOccurrences(out, w.Occurrences),
+		assertBaseline(out, w, storage),
+		assertCompletionOrder(w.CompletedInOrder, storage),
+		assertRecentTimestamps(out, w.RecentTimestamps, now()),
+		assertScan(out, w.Scan, storage),
+		assertDynamic(out, w.Dynamic, storage),
+	)
+}
+
 func assertWanted(out string, w Assertion) error {
 	var errs []error
 	for _, want := range w.Output {
@@ -249,12 +909,465 @@ func assertPattern(out string, patterns []string) error {
 	return nil
 }
 
-func assertErrors(stderr *bytes.Buffer, errrs []string) error {
+func assertPatternCounts(out string, w Assertion) error {
+	patterns := make([]string, 0, len(w.PatternCounts))
+	for pattern := range w.PatternCounts {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
 	var errs []error
-	for _, want := range errrs {
-		if !strings.Contains(stderr.String(), want) {
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("count pattern \"%s\" did not compile: %w", pattern, err))
+			continue
+		}
+
+		want, got := w.PatternCounts[pattern], len(re.FindAllStringIndex(out, -1))
+		if w.ExactPatternCounts && got != want {
+			errs = append(errs, fmt.Errorf("pattern \"%s\" matched %d times, want exactly %d", pattern, got, want))
+		}
+		if !w.ExactPatternCounts && got < want {
+			errs = append(errs, fmt.Errorf("pattern \"%s\" matched %d times, want at least %d", pattern, got, want))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must match pattern counts", errors.Join(errs...))
+	}
+	return nil
+}
+
+func assertLines(out string, w Assertion) error {
+	indexes := make([]int, 0, len(w.LineAssertions))
+	for index := range w.LineAssertions {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	lines := splitLines(out)
+	var errs []error
+	for _, index := range indexes {
+		want := w.LineAssertions[index]
+		if index < 0 || index >= len(lines) {
+			errs = append(errs, fmt.Errorf(
+				"line %d is out of range, standard output has %d lines", index, len(lines),
+			))
+			continue
+		}
+
+		line := lines[index]
+		if w.Strict && line != want {
+			errs = append(errs, fmt.Errorf("strict match on line %d got \"%s\" want \"%s\"", index, line, want))
+		}
+
+		if !w.Strict && !strings.Contains(line, want) {
+			errs = append(errs, fmt.Errorf("didn't find \"%s\" in line %d: \"%s\"", want, index, line))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must find lines", errors.Join(errs...))
+	}
+	return nil
+}
+
+func assertOrderedLines(out string, want []string) error {
+	if want == nil {
+		return nil
+	}
+
+	got := splitLines(out)
+	for i := 0; i < len(got) || i < len(want); i++ {
+		var err error
+		switch {
+		case i >= len(got):
+			err = fmt.Errorf("line %d is missing, want \"%s\"", i, want[i])
+		case i >= len(want):
+			err = fmt.Errorf("line %d \"%s\" is unexpected, want %d lines", i, got[i], len(want))
+		case got[i] != want[i]:
+			err = fmt.Errorf("line %d got \"%s\" want \"%s\"", i, got[i], want[i])
+		}
+		if err != nil {
+			return NewPrefixedError("must match lines", err)
+		}
+	}
+	return nil
+}
+
+func assertLineSet(out string, want []string) error {
+	if want == nil {
+		return nil
+	}
+
+	got := make(map[string]bool)
+	var unexpected []string
+	wanted := make(map[string]bool, len(want))
+	for _, line := range want {
+		wanted[line] = true
+	}
+	for _, line := range splitLines(out) {
+		if strings.TrimSpace(line) == "" || got[line] {
+			continue
+		}
+		got[line] = true
+		if !wanted[line] {
+			unexpected = append(unexpected, line)
+		}
+	}
+
+	var missing []string
+	for _, line := range want {
+		if !got[line] {
+			missing = append(missing, line)
+			// Repeated lines are only reported once.
+			got[line] = true
+		}
+	}
+
+	var errs []error
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("missing %d lines: %q", len(missing), missing))
+	}
+	if len(unexpected) > 0 {
+		errs = append(errs, fmt.Errorf("found %d unexpected lines: %q", len(unexpected), unexpected))
+	}
+	if len(errs) > 0 {
+		return NewPrefixedError("must match line set", errors.Join(errs...))
+	}
+	return nil
+}
+
+func assertNumeric(out string, numeric []NumericAssertion) error {
+	var errs []error
+	for _, n := range numeric {
+		re, err := regexp.Compile(n.Pattern)
+		if err != nil {
+			errs = append(errs,
+				fmt.Errorf("numeric pattern \"%s\" did not compile", n.Pattern),
+			)
+			continue
+		}
+
+		match := re.FindStringSubmatch(out)
+		if match == nil {
+			errs = append(errs,
+				fmt.Errorf("couldn't match numeric pattern \"%s\" to standard output: \"%s\"", n.Pattern, out),
+			)
+			continue
+		}
+
+		captured := firstGroup(match)
+		actual, err := strconv.ParseFloat(strings.TrimSpace(captured), 64)
+		if err != nil {
+			errs = append(errs,
+				fmt.Errorf("value \"%s\" captured by pattern \"%s\" is not a number", captured, n.Pattern),
+			)
+			continue
+		}
+
+		if math.Abs(actual-n.Expected) > n.Tolerance {
+			errs = append(errs, fmt.Errorf(
+				"value %v captured by pattern \"%s\" is not within %v of %v",
+				actual, n.Pattern, n.Tolerance, n.Expected,
+			))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must find numeric values", errors.Join(errs...))
+	}
+	return nil
+}
+
+func assertMonotonic(out string, m MonotonicAssertion) error {
+	if m.Pattern == "" {
+		return nil
+	}
+
+	if err := monotonic(out, m); err != nil {
+		return NewPrefixedError("must be monotonic", err)
+	}
+	return nil
+}
+
+func assertSorted(out string, w Assertion) error {
+	if !w.SortedAscending && !w.SortedDescending {
+		return nil
+	}
+
+	filtered, err := filterLines(out, w.SortedLineFilter)
+	if err != nil {
+		return NewPrefixedError("must be sorted", err)
+	}
+
+	order, outOfOrder := "ascending", func(prev, line string) bool { return line < prev }
+	if w.SortedDescending {
+		order, outOfOrder = "descending", func(prev, line string) bool { return line > prev }
+	}
+
+	lines := splitLines(filtered)
+	for i := 1; i < len(lines); i++ {
+		if outOfOrder(lines[i-1], lines[i]) {
+			return NewPrefixedError("must be sorted",
+				fmt.Errorf("line \"%s\" is not in %s order after line \"%s\"", lines[i], order, lines[i-1]),
+			)
+		}
+	}
+	return nil
+}
+
+func monotonic(out string, m MonotonicAssertion) error {
+	re, err := regexp.Compile(m.Pattern)
+	if err != nil {
+		return fmt.Errorf("monotonic pattern \"%s\" did not compile", m.Pattern)
+	}
+
+	var matched int
+	var previous float64
+	var previousLine int
+	for i, line := range splitLines(out) {
+		match := re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		captured := firstGroup(match)
+		value, err := strconv.ParseFloat(strings.TrimSpace(captured), 64)
+		if err != nil {
+			return fmt.Errorf(
+				"value \"%s\" captured on line %d by pattern \"%s\" is not a number", captured, i, m.Pattern,
+			)
+		}
+
+		if matched > 0 && (value < previous || (value == previous && !m.AllowEqual)) {
+			return fmt.Errorf(
+				"value %v on line %d is not increasing from value %v on line %d",
+				value, i, previous, previousLine,
+			)
+		}
+		matched++
+		previous, previousLine = value, i
+	}
+
+	if matched == 0 {
+		return fmt.Errorf(
+			"couldn't match monotonic pattern \"%s\" to standard output: \"%s\"", m.Pattern, out,
+		)
+	}
+	return nil
+}
+
+func assertFuzzy(out string, matches []FuzzyMatch) error {
+	var errs []error
+	for _, m := range matches {
+		if distance := substringDistance(m.Want, out); distance > m.MaxDistance {
+			errs = append(errs, fmt.Errorf(
+				"best match for \"%s\" has a distance of %d, over the maximum %d, in standard output: \"%s\"",
+				m.Want, distance, m.MaxDistance, out,
+			))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must find fuzzy matches", errors.Join(errs...))
+	}
+	return nil
+}
+
+// substringDistance returns the smallest Levenshtein distance between want and
+// any substring of s.
+func substringDistance(want, s string) int {
+	w, r := []rune(want), []rune(s)
+
+	// Row i holds the distances between w[:i] and the substrings ending at
+	// each position of s. Starting anywhere in s is free, so the first row is
+	// all zeros.
+	prev := make([]int, len(r)+1)
+	curr := make([]int, len(r)+1)
+	for i := 1; i <= len(w); i++ {
+		curr[0] = i
+		for j := 1; j <= len(r); j++ {
+			cost := 1
+			if w[i-1] == r[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+
+	best := prev[0]
+	for _, d := range prev[1:] {
+		best = minInt(best, d)
+	}
+	return best
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func assertEmpty(out, stderr string, w Assertion) error {
+	var errs []error
+	if w.EmptyOutput && strings.TrimSpace(out) != "" {
+		errs = append(errs, fmt.Errorf("standard output is not empty: \"%s\"", out))
+	}
+	if w.EmptyErrors && strings.TrimSpace(stderr) != "" {
+		errs = append(errs, fmt.Errorf("standard error is not empty: \"%s\"", stderr))
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must be empty", errors.Join(errs...))
+	}
+	return nil
+}
+
+// maxReportedOffsets is the maximum number of control character offsets
+// reported on failure.
+const maxReportedOffsets = 10
+
+func assertValidUTF8(out string, enabled bool) error {
+	if !enabled || utf8.ValidString(out) {
+		return nil
+	}
+
+	offset := invalidUTF8Offset(out)
+	end := offset + utf8.UTFMax
+	if end > len(out) {
+		end = len(out)
+	}
+	return NewPrefixedError("must be valid UTF-8", fmt.Errorf(
+		"found an invalid UTF-8 sequence at byte offset %d in standard output: %q", offset, out[offset:end],
+	))
+}
+
+// invalidUTF8Offset returns the byte offset of the first invalid UTF-8
+// sequence in out, or -1 when it's valid.
+func invalidUTF8Offset(out string) int {
+	for i := 0; i < len(out); {
+		r, size := utf8.DecodeRuneInString(out[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
+
+func assertNoControlChars(out string, enabled bool) error {
+	if !enabled {
+		return nil
+	}
+
+	var offsets []string
+	var found, first int
+	for i, r := range out {
+		if !unicode.IsControl(r) || r == '\t' || r == '\n' {
+			continue
+		}
+		if found == 0 {
+			first = i
+		}
+		if found++; found <= maxReportedOffsets {
+			offsets = append(offsets, fmt.Sprintf("%d (%U)", i, r))
+		}
+	}
+	if found == 0 {
+		return nil
+	}
+
+	if found > maxReportedOffsets {
+		offsets = append(offsets, fmt.Sprintf("and %d more", found-maxReportedOffsets))
+	}
+	// Only the surroundings of the first character are reported, since the
+	// output can be large binary data.
+	return NewPrefixedError("must not have control characters", fmt.Errorf(
+		"found control characters at byte offsets %s in standard output, around the first: %q",
+		strings.Join(offsets, ", "), outputWindow(out, first),
+	))
+}
+
+// outputWindowSize is the number of bytes reported on each side of an offset
+// by outputWindow.
+const outputWindowSize = 16
+
+// outputWindow returns the bytes of out around the offset, so a failure can
+// point to its location without including the whole output.
+func outputWindow(out string, offset int) string {
+	start, end := offset-outputWindowSize, offset+outputWindowSize
+	if start < 0 {
+		start = 0
+	}
+	if end > len(out) {
+		end = len(out)
+	}
+	return out[start:end]
+}
+
+func assertBinary(out string, w Assertion) error {
+	var errs []error
+	if len(w.HexContains) > 0 {
+		encoded := hex.EncodeToString([]byte(out))
+		for _, want := range w.HexContains {
+			fragment := strings.ToLower(strings.Join(strings.Fields(want), ""))
+			if !strings.Contains(encoded, fragment) {
+				errs = append(errs, fmt.Errorf(
+					"didn't find hex \"%s\" in the %d bytes of standard output starting with: %s",
+					fragment, len(out), hexPrefix(encoded),
+				))
+			}
+		}
+	}
+
+	if w.OutputByteLen != nil && len(out) != *w.OutputByteLen {
+		errs = append(errs, fmt.Errorf("standard output has %d bytes, want %d", len(out), *w.OutputByteLen))
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must match binary output", errors.Join(errs...))
+	}
+	return nil
+}
+
+// maxHexPrefix is the maximum number of hex digits of the output reported by
+// the HexContains failures.
+const maxHexPrefix = 64
+
+// hexPrefix truncates the hex encoded output, since it's twice the size of the
+// output, which can be large binary data.
+func hexPrefix(encoded string) string {
+	if len(encoded) <= maxHexPrefix {
+		return encoded
+	}
+	return encoded[:maxHexPrefix] + "..."
+}
+
+func assertErrors(stderr string, w Assertion) error {
+	if len(w.Errors) == 0 {
+		return nil
+	}
+
+	filtered, err := filterLines(stderr, w.ErrorLineFilter)
+	if err != nil {
+		return NewPrefixedError("must find errors", err)
+	}
+
+	var errs []error
+	for _, want := range w.Errors {
+		// The surrounding whitespace is ignored, so the trailing newline most
+		// commands print doesn't need to be part of the expected error.
+		if w.StrictErrors && strings.TrimSpace(filtered) != strings.TrimSpace(want) {
+			errs = append(errs, fmt.Errorf("strict match got \"%s\" want \"%s\" in standard error", filtered, want))
+		}
+
+		if !w.StrictErrors && !strings.Contains(filtered, want) {
 			errs = append(errs,
-				fmt.Errorf("didn't find \"%s\" in standard error: \"%s\"", want, stderr.String()),
+				fmt.Errorf("didn't find \"%s\" in standard error: \"%s\"", want, stderr),
 			)
 		}
 	}
@@ -265,6 +1378,45 @@ func assertErrors(stderr *bytes.Buffer, errrs []string) error {
 	return nil
 }
 
+// maxSampleLines is the maximum number of matching lines reported when there
+// are too many of them.
+const maxSampleLines = 3
+
+func assertMaxErrorMatches(stderr string, limits []ErrorMatchLimit) error {
+	var errs []error
+	for _, limit := range limits {
+		re, err := regexp.Compile(limit.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error match pattern \"%s\" did not compile: %w", limit.Pattern, err))
+			continue
+		}
+
+		var matches []string
+		for _, line := range splitLines(stderr) {
+			if re.MatchString(line) {
+				matches = append(matches, line)
+			}
+		}
+		if len(matches) <= limit.Max {
+			continue
+		}
+
+		sample := matches
+		if len(sample) > maxSampleLines {
+			sample = sample[:maxSampleLines]
+		}
+		errs = append(errs, fmt.Errorf(
+			"pattern \"%s\" matched %d lines in standard error, want at most %d, e.g. \"%s\"",
+			limit.Pattern, len(matches), limit.Max, strings.Join(sample, "\", \""),
+		))
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must not exceed error matches", errors.Join(errs...))
+	}
+	return nil
+}
+
 func assertDynamic(out string, dynamic []string, storage teststorage.Storage) error {
 	var errs []error
 	for _, key := range dynamic {
@@ -287,7 +1439,7 @@ func assertDynamic(out string, dynamic []string, storage teststorage.Storage) er
 	return nil
 }
 
-func assertMustNot(out, stderr string, not Assertion) error {
+func assertMustNot(out, stderr string, not Assertion, storage teststorage.Storage) error {
 	var errs []error
 	for _, mustNot := range not.Output {
 		if not.Strict && out == mustNot {
@@ -299,14 +1451,151 @@ func assertMustNot(out, stderr string, not Assertion) error {
 		}
 	}
 
+	filtered, err := filterLines(stderr, not.ErrorLineFilter)
+	if err != nil {
+		errs = append(errs, err)
+	}
 	for _, mustNot := range not.Errors {
-		if strings.Contains(stderr, mustNot) {
+		if not.StrictErrors && strings.TrimSpace(filtered) == strings.TrimSpace(mustNot) {
+			errs = append(errs, fmt.Errorf("strict match got \"%s\" must not: \"%s\" in standard error", filtered, mustNot))
+		}
+
+		if !not.StrictErrors && strings.Contains(filtered, mustNot) {
 			errs = append(errs, fmt.Errorf("found \"%s\" in standard error:\"%s\"", mustNot, stderr))
 		}
 	}
 
+	// The dynamic keys are resolved the same way as in assertDynamic.
+	for _, key := range not.Dynamic {
+		value := key
+		if v, ok := storage.Get(key); ok {
+			value = v
+		}
+		if strings.Contains(out, value) {
+			errs = append(errs,
+				fmt.Errorf("found dynamic key \"%s\" with value \"%s\" in standard output: \"%s\"", key, value, out),
+			)
+		}
+	}
+
 	if len(errs) > 0 {
 		return NewPrefixedError("must not find values", errors.Join(errs...))
 	}
 	return nil
 }
+
+// extractSection returns the part of out between the matches of the start and
+// end patterns. An empty pattern matches the start or the end of out.
+func extractSection(out, start, end string) (string, error) {
+	from := 0
+	if start != "" {
+		re, err := regexp.Compile(start)
+		if err != nil {
+			return "", fmt.Errorf("section start \"%s\" did not compile: %w", start, err)
+		}
+		loc := re.FindStringIndex(out)
+		if loc == nil {
+			return "", fmt.Errorf("couldn't match section start \"%s\" to standard output: \"%s\"", start, out)
+		}
+		from = loc[1]
+	}
+
+	to := len(out)
+	if end != "" {
+		re, err := regexp.Compile(end)
+		if err != nil {
+			return "", fmt.Errorf("section end \"%s\" did not compile: %w", end, err)
+		}
+		loc := re.FindStringIndex(out[from:])
+		if loc == nil {
+			return "", fmt.Errorf("couldn't match section end \"%s\" to standard output: \"%s\"", end, out)
+		}
+		to = from + loc[0]
+	}
+	return out[from:to], nil
+}
+
+// stripLinePrefix removes the prefix matching the pattern from each line.
+func stripLinePrefix(out, pattern string) (string, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")")
+	if err != nil {
+		return "", fmt.Errorf("line prefix pattern \"%s\" did not compile: %w", pattern, err)
+	}
+
+	lines := strings.SplitAfter(out, "\n")
+	for i, line := range lines {
+		lines[i] = re.ReplaceAllString(line, "")
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// dedupeLines removes the lines equal to the previous line, or to any of the
+// previous lines when global is set.
+func dedupeLines(out string, global bool) string {
+	seen := make(map[string]bool)
+	var previous string
+	var b strings.Builder
+	for i, line := range strings.SplitAfter(out, "\n") {
+		// The last line may lack the line ending, but it's still a duplicate.
+		key := strings.TrimSuffix(line, "\n")
+		if (i > 0 && key == previous) || (global && seen[key]) {
+			continue
+		}
+		seen[key], previous = true, key
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// gunzip returns the decompressed gzip data.
+func gunzip(data string) (string, error) {
+	r, err := gzip.NewReader(strings.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(decompressed), nil
+}
+
+// filterLines returns the lines of out which match the pattern, or out as is
+// when the pattern is empty.
+func filterLines(out, pattern string) (string, error) {
+	if pattern == "" {
+		return out, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("line filter \"%s\" did not compile: %w", pattern, err)
+	}
+
+	var b strings.Builder
+	for _, line := range splitLines(out) {
+		if re.MatchString(line) {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// splitLines splits the output into lines, ignoring the trailing newline.
+func splitLines(out string) []string {
+	if out == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+}
+
+// firstGroup returns the value captured by the first group of a regex match,
+// or the whole match when the regex has no groups.
+func firstGroup(match []string) string {
+	if len(match) > 1 {
+		return match[1]
+	}
+	return match[0]
+}
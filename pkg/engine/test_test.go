@@ -0,0 +1,1256 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func Test_assertLines(t *testing.T) {
+	const out = "NAME    STATUS\ncluster started\nother   stopped\n"
+	tests := []struct {
+		name string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "Matches a line partially",
+			w:    Assertion{LineAssertions: map[int]string{1: "started"}},
+		},
+		{
+			name: "Matches a line strictly",
+			w: Assertion{Strict: true, LineAssertions: map[int]string{
+				0: "NAME    STATUS",
+				2: "other   stopped",
+			}},
+		},
+		{
+			name: "Fails a strict match",
+			w:    Assertion{Strict: true, LineAssertions: map[int]string{1: "started"}},
+			err:  "strict match on line 1 got \"cluster started\" want \"started\"",
+		},
+		{
+			name: "Fails when the line doesn't contain the value",
+			w:    Assertion{LineAssertions: map[int]string{2: "started"}},
+			err:  "didn't find \"started\" in line 2: \"other   stopped\"",
+		},
+		{
+			name: "Fails when the line is out of range",
+			w:    Assertion{LineAssertions: map[int]string{3: "started"}},
+			err:  "line 3 is out of range, standard output has 3 lines",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertLines(out, tt.w)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertLines() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertLines() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAssertions_Warnings(t *testing.T) {
+	tests := []struct {
+		name   string
+		a      Assertions
+		stdout string
+		stderr string
+		err    string
+	}{
+		{
+			name:   "Returns no warnings when the Should assertions are met",
+			a:      Assertions{Should: Assertion{Output: []string{"something"}}},
+			stdout: "something\n",
+		},
+		{
+			name: "Returns the Should assertions which weren't met",
+			a: Assertions{Should: Assertion{
+				Output: []string{"something"},
+				Errors: []string{"deprecated"},
+			}},
+			stdout: "something\n",
+			stderr: "a warning\n",
+			err:    "should\nmust find errors\ndidn't find \"deprecated\" in standard error: \"a warning\n\"",
+		},
+		{
+			name:   "Ignores the Must assertions",
+			a:      Assertions{Must: Assertion{Output: []string{"something else"}}},
+			stdout: "something\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.a.Warnings(
+				bytes.NewBufferString(tt.stdout), bytes.NewBufferString(tt.stderr),
+				teststorage.NewSafeMap(),
+			)
+			if tt.err == "" && err != nil {
+				t.Errorf("Assertions.Warnings() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || err.Error() != tt.err) {
+				t.Errorf("Assertions.Warnings() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAssertions_Ensure(t *testing.T) {
+	exitErr := exec.Command(mockBin, enginetest.MockCommand{ExitCode: 1}.Args()...).Run()
+	_, notFoundErr := exec.LookPath("anunexistingbinary")
+	notExecutableErr := exec.Command("./").Run()
+	tests := []struct {
+		name   string
+		a      Assertions
+		stdout string
+		stderr string
+		err    error
+		want   string
+	}{
+		{
+			name:   "Passes when the assertions are met",
+			a:      Assertions{Must: Assertion{Output: []string{"something"}}},
+			stdout: "something\n",
+		},
+		{
+			name: "Fails when the command errors unexpectedly",
+			err:  exitErr,
+			want: "command: \"false\"\nerror = exit status 1, wantErr = false, stderr = ",
+		},
+		{
+			name: "Passes when the command errors and WantErr is set",
+			a:    Assertions{WantErr: true},
+			err:  exitErr,
+		},
+		{
+			name: "Fails when the command exits zero and WantErr is set",
+			a:    Assertions{WantErr: true},
+			want: "command: \"false\"\nexited zero but expected an error, stderr = ",
+		},
+		{
+			name: "Leaves a command which succeeds despite the timeout behavior to assertTimeout",
+			a:    Assertions{Timeout: &TimeoutBehavior{Within: time.Second}},
+		},
+		{
+			name:   "Passes when the command errors with a standard error and WantErrWithStderr is set",
+			a:      Assertions{WantErr: true, WantErrWithStderr: true},
+			err:    exitErr,
+			stderr: "invalid argument\n",
+		},
+		{
+			name:   "Fails when the command errors silently and WantErrWithStderr is set",
+			a:      Assertions{WantErr: true, WantErrWithStderr: true},
+			err:    exitErr,
+			stderr: "\n",
+			want:   "command: \"false\"\nerrored but produced no standard error, error = exit status 1",
+		},
+		{
+			name: "Fails when the binary is not found and WantErr is set",
+			a:    Assertions{WantErr: true},
+			err:  notFoundErr,
+			want: "command: \"false\"\nfailed to start: " + notFoundErr.Error(),
+		},
+		{
+			name: "Fails when the binary is not executable and CanError is set",
+			a:    Assertions{CanError: true},
+			err:  notExecutableErr,
+			want: "command: \"false\"\nfailed to start: " + notExecutableErr.Error(),
+		},
+		{
+			name: "Passes when the binary is not found and CanFailToStart is set",
+			a:    Assertions{WantErr: true, CanFailToStart: true},
+			err:  notFoundErr,
+		},
+		{
+			name: "Passes when the exit code is within the range",
+			a:    Assertions{ExitCodeRange: &ExitCodeRange{Min: 1, Max: 63}},
+			err:  exitErr,
+		},
+		{
+			name: "Fails when the exit code is not within the range",
+			a:    Assertions{ExitCodeRange: &ExitCodeRange{Min: 64, Max: 255}},
+			err:  exitErr,
+			want: "command: \"false\"\nexit code 1 is not within [64, 255], stderr = ",
+		},
+		{
+			name: "Fails when the command succeeds and the range excludes 0",
+			a:    Assertions{ExitCodeRange: &ExitCodeRange{Min: 1, Max: 255}},
+			want: "command: \"false\"\nexit code 0 is not within [1, 255], stderr = ",
+		},
+		{
+			name: "Returns the comparator error",
+			a: Assertions{Comparator: func(stdout, stderr string) error {
+				if stdout != stderr {
+					return errors.New("the standard output and error differ")
+				}
+				return nil
+			}},
+			stdout: "something\n",
+			want:   "assertion\ncomparator\nthe standard output and error differ",
+		},
+		{
+			name: "Returns the assertion errors",
+			a: Assertions{
+				Must: Assertion{Output: []string{"something else"}},
+				Not:  Assertion{Errors: []string{"warning"}},
+			},
+			stdout: "something\n",
+			stderr: "a warning\n",
+			want: "assertion\nmust find\ndidn't find \"something else\" in standard output: \"something\n\"\n" +
+				"must not find values\nfound \"warning\" in standard error:\"a warning\n\"",
+		},
+		{
+			name: "Prepends the assertion messages to their errors",
+			a: Assertions{
+				Must: Assertion{Message: "the cluster must have started", Output: []string{"started"}},
+				Not:  Assertion{Message: "the cluster must not warn", Errors: []string{"warning"}},
+			},
+			stdout: "stopped\n",
+			stderr: "a warning\n",
+			want: "assertion\nthe cluster must have started\nmust find\ndidn't find \"started\" in standard output: \"stopped\n\"\n" +
+				"the cluster must not warn\nmust not find values\nfound \"warning\" in standard error:\"a warning\n\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.a.Ensure(
+				bytes.NewBufferString(tt.stdout), bytes.NewBufferString(tt.stderr),
+				tt.err, teststorage.NewSafeMap(), "false",
+			)
+			if tt.want == "" && err != nil {
+				t.Errorf("Assertions.Ensure() error = %v", err)
+			}
+			if tt.want != "" && (err == nil || err.Error() != tt.want) {
+				t.Errorf("Assertions.Ensure() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_assertNumeric(t *testing.T) {
+	const out = "Finished in 1.52s (took 3 retries)\n"
+	tests := []struct {
+		name    string
+		numeric []NumericAssertion
+		err     string
+	}{
+		{
+			name: "Matches the captured group within the tolerance",
+			numeric: []NumericAssertion{
+				{Pattern: `in ([0-9.]+)s`, Expected: 1.5, Tolerance: 0.1},
+				{Pattern: `[0-9]+`, Expected: 1, Tolerance: 1},
+			},
+		},
+		{
+			name:    "Fails when the value is not within the tolerance",
+			numeric: []NumericAssertion{{Pattern: `in ([0-9.]+)s`, Expected: 1, Tolerance: 0.5}},
+			err:     "value 1.52 captured by pattern \"in ([0-9.]+)s\" is not within 0.5 of 1",
+		},
+		{
+			name:    "Fails when the pattern doesn't match",
+			numeric: []NumericAssertion{{Pattern: `took ([0-9]+)ms`}},
+			err:     "couldn't match numeric pattern \"took ([0-9]+)ms\"",
+		},
+		{
+			name:    "Fails when the captured value is not a number",
+			numeric: []NumericAssertion{{Pattern: `(Finished)`}},
+			err:     "value \"Finished\" captured by pattern \"(Finished)\" is not a number",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertNumeric(out, tt.numeric)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertNumeric() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertNumeric() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertMonotonic(t *testing.T) {
+	const out = "seq=1 ok\nseq=2 ok\nretrying\nseq=2 ok\nseq=5 ok\n"
+	tests := []struct {
+		name string
+		m    MonotonicAssertion
+		err  string
+	}{
+		{
+			name: "Passes when the values are not decreasing and equal values are allowed",
+			m:    MonotonicAssertion{Pattern: `seq=([0-9]+)`, AllowEqual: true},
+		},
+		{
+			name: "Fails on the first pair which is not strictly increasing",
+			m:    MonotonicAssertion{Pattern: `seq=([0-9]+)`},
+			err:  "value 2 on line 3 is not increasing from value 2 on line 1",
+		},
+		{
+			name: "Fails when no lines match",
+			m:    MonotonicAssertion{Pattern: `ts=([0-9]+)`},
+			err:  "couldn't match monotonic pattern \"ts=([0-9]+)\"",
+		},
+		{
+			name: "Does nothing when the pattern is empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertMonotonic(out, tt.m)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertMonotonic() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertMonotonic() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertMustNot(t *testing.T) {
+	storage := teststorage.NewSafeMap()
+	storage.Set("secret", "MySuperSecretPassword")
+	tests := []struct {
+		name   string
+		not    Assertion
+		stdout string
+		stderr string
+		err    string
+	}{
+		{
+			name:   "Passes when the stored value is not found",
+			not:    Assertion{Dynamic: []string{"secret"}},
+			stdout: "logged in as admin\n",
+		},
+		{
+			name:   "Fails when the stored value is found",
+			not:    Assertion{Dynamic: []string{"secret"}},
+			stdout: "logged in with MySuperSecretPassword\n",
+			err:    "found dynamic key \"secret\" with value \"MySuperSecretPassword\" in standard output",
+		},
+		{
+			name:   "Fails when the unstored key is found",
+			not:    Assertion{Dynamic: []string{"admin"}},
+			stdout: "logged in as admin\n",
+			err:    "found dynamic key \"admin\" with value \"admin\" in standard output",
+		},
+		{
+			name:   "Fails when the error is found",
+			not:    Assertion{Errors: []string{"deprecated"}},
+			stderr: "flag is deprecated",
+			err:    "found \"deprecated\" in standard error:\"flag is deprecated\"",
+		},
+		{
+			name:   "Ignores the errors in the lines filtered out",
+			not:    Assertion{ErrorLineFilter: `^(WARN|ERROR)`, Errors: []string{"deprecated"}},
+			stderr: "INFO flag is deprecated\nWARN slow request\n",
+		},
+		{
+			name:   "Passes when the error only partially matches strictly",
+			not:    Assertion{StrictErrors: true, Errors: []string{"deprecated"}},
+			stderr: "flag is deprecated\n",
+		},
+		{
+			name:   "Fails when the error matches strictly",
+			not:    Assertion{StrictErrors: true, Errors: []string{"flag is deprecated"}},
+			stderr: "flag is deprecated\n",
+			err:    "strict match got \"flag is deprecated\n\" must not: \"flag is deprecated\" in standard error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertMustNot(tt.stdout, tt.stderr, tt.not, storage)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertMustNot() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertMustNot() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertFuzzy(t *testing.T) {
+	const out = "Successfully conected to the cluster\n"
+	tests := []struct {
+		name    string
+		matches []FuzzyMatch
+		err     string
+	}{
+		{
+			name:    "Matches the exact value with no distance",
+			matches: []FuzzyMatch{{Want: "the cluster"}},
+		},
+		{
+			name:    "Matches a misspelled value within the distance",
+			matches: []FuzzyMatch{{Want: "connected", MaxDistance: 1}},
+		},
+		{
+			name:    "Fails when the best match is over the distance",
+			matches: []FuzzyMatch{{Want: "connected to a cluster", MaxDistance: 1}},
+			err:     "best match for \"connected to a cluster\" has a distance of 4, over the maximum 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertFuzzy(out, tt.matches)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertFuzzy() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertFuzzy() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertErrors(t *testing.T) {
+	const stderr = "Error: cluster not found\n"
+	tests := []struct {
+		name string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "Finds the error partially",
+			w:    Assertion{Errors: []string{"not found"}},
+		},
+		{
+			name: "Matches the error strictly ignoring the trailing newline",
+			w:    Assertion{StrictErrors: true, Errors: []string{"Error: cluster not found"}},
+		},
+		{
+			name: "Fails a strict match",
+			w:    Assertion{StrictErrors: true, Errors: []string{"not found"}},
+			err:  "strict match got \"Error: cluster not found\n\" want \"not found\" in standard error",
+		},
+		{
+			name: "Ignores Strict, which only applies to the standard output",
+			w:    Assertion{Strict: true, Errors: []string{"not found"}},
+		},
+		{
+			name: "Fails when the error isn't found",
+			w:    Assertion{Errors: []string{"unauthorized"}},
+			err:  "didn't find \"unauthorized\" in standard error",
+		},
+		{
+			name: "Matches strictly the filtered lines",
+			w:    Assertion{StrictErrors: true, ErrorLineFilter: `^Error:`, Errors: []string{"Error: cluster not found"}},
+		},
+		{
+			name: "Fails when the error is only in the lines filtered out",
+			w:    Assertion{ErrorLineFilter: `^WARN`, Errors: []string{"not found"}},
+			err:  "didn't find \"not found\" in standard error: \"Error: cluster not found\n\"",
+		},
+		{
+			name: "Fails when the filter doesn't compile",
+			w:    Assertion{ErrorLineFilter: `(`, Errors: []string{"not found"}},
+			err:  "line filter \"(\" did not compile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertErrors(stderr, tt.w)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertErrors() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertErrors() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertMust_base64Decode(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "Asserts the decoded output",
+			out:  "eyJzdGF0dXMiOiAib2sifQ==\n",
+			w:    Assertion{Base64Decode: true, Output: []string{`"status": "ok"`}},
+		},
+		{
+			name: "Fails the assertions on the decoded output",
+			out:  "eyJzdGF0dXMiOiAib2sifQ==\n",
+			w:    Assertion{Base64Decode: true, Output: []string{"eyJzdGF0dXMi"}},
+			err:  "didn't find \"eyJzdGF0dXMi\" in standard output: \"{\"status\": \"ok\"}\"",
+		},
+		{
+			name: "Fails when the output isn't valid base64",
+			out:  "not base64\n",
+			w:    Assertion{Base64Decode: true},
+			err:  "standard output is not valid base64: illegal base64 data at input byte 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertMust(tt.out, "", tt.w, teststorage.NewSafeMap(), time.Now)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertMust() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertMust() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertMust_gunzipOutput(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write([]byte(`{"status": "ok"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		out  string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "Asserts the decompressed output",
+			out:  compressed.String(),
+			w:    Assertion{GunzipOutput: true, JSON: `{"status": "ok"}`},
+		},
+		{
+			name: "Decompresses the base64 decoded output",
+			out:  base64.StdEncoding.EncodeToString(compressed.Bytes()) + "\n",
+			w:    Assertion{Base64Decode: true, GunzipOutput: true, Output: []string{`"status": "ok"`}},
+		},
+		{
+			name: "Fails when the output isn't valid gzip",
+			out:  `{"status": "ok"}`,
+			w:    Assertion{GunzipOutput: true},
+			err:  "standard output is not valid gzip: gzip: invalid header",
+		},
+		{
+			name: "Fails when the output is truncated",
+			out:  compressed.String()[:compressed.Len()-4],
+			w:    Assertion{GunzipOutput: true},
+			err:  "standard output is not valid gzip: unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertMust(tt.out, "", tt.w, teststorage.NewSafeMap(), time.Now)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertMust() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertMust() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertMust_expectedFunc(t *testing.T) {
+	storage := teststorage.NewSafeMap()
+	storage.Set("cluster_id", "a1b2c3")
+	fromStorage := func(s teststorage.Storage) ([]string, error) {
+		id, ok := s.Get("cluster_id")
+		if !ok {
+			return nil, errors.New("cluster_id not found")
+		}
+		return []string{"cluster " + id}, nil
+	}
+
+	tests := []struct {
+		name string
+		out  string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "Asserts the computed output",
+			out:  "deleted cluster a1b2c3\n",
+			w:    Assertion{Output: []string{"deleted"}, ExpectedFunc: fromStorage},
+		},
+		{
+			name: "Fails when the computed output isn't found",
+			out:  "deleted cluster d4e5f6\n",
+			w:    Assertion{ExpectedFunc: fromStorage},
+			err:  "didn't find \"cluster a1b2c3\" in standard output",
+		},
+		{
+			name: "Fails when the function errors",
+			out:  "deleted cluster a1b2c3\n",
+			w: Assertion{ExpectedFunc: func(teststorage.Storage) ([]string, error) {
+				return nil, errors.New("clock unavailable")
+			}},
+			err: "must compute the expected output\nclock unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertMust(tt.out, "", tt.w, storage, time.Now)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertMust() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertMust() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertEmpty(t *testing.T) {
+	tests := []struct {
+		name   string
+		w      Assertion
+		stdout string
+		stderr string
+		err    string
+	}{
+		{
+			name:   "Passes when the outputs only have whitespace",
+			w:      Assertion{EmptyOutput: true, EmptyErrors: true},
+			stdout: "\n",
+			stderr: " \t\n",
+		},
+		{
+			name:   "Ignores the outputs when unset",
+			stdout: "something\n",
+			stderr: "a warning\n",
+		},
+		{
+			name:   "Fails when the standard output isn't empty",
+			w:      Assertion{EmptyOutput: true},
+			stdout: "something\n",
+			err:    "standard output is not empty: \"something\n\"",
+		},
+		{
+			name:   "Fails when the standard error isn't empty",
+			w:      Assertion{EmptyErrors: true},
+			stderr: "a warning\n",
+			err:    "standard error is not empty: \"a warning\n\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertEmpty(tt.stdout, tt.stderr, tt.w)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertEmpty() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertEmpty() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertPatternCounts(t *testing.T) {
+	const out = "INFO starting\nWARN slow disk\nINFO ready\nWARN slow network\n"
+	tests := []struct {
+		name string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "Passes when the patterns match at least the count",
+			w:    Assertion{PatternCounts: map[string]int{`(?m)^INFO`: 1, `WARN slow \w+`: 2}},
+		},
+		{
+			name: "Passes when the patterns match exactly the count",
+			w:    Assertion{ExactPatternCounts: true, PatternCounts: map[string]int{`(?m)^WARN`: 2}},
+		},
+		{
+			name: "Fails when the pattern matches fewer times",
+			w:    Assertion{PatternCounts: map[string]int{`(?m)^INFO`: 3}},
+			err:  "pattern \"(?m)^INFO\" matched 2 times, want at least 3",
+		},
+		{
+			name: "Fails when the pattern doesn't match exactly the count",
+			w:    Assertion{ExactPatternCounts: true, PatternCounts: map[string]int{`slow`: 1}},
+			err:  "pattern \"slow\" matched 2 times, want exactly 1",
+		},
+		{
+			name: "Ignores Strict, which only applies to the standard output",
+			w:    Assertion{Strict: true, PatternCounts: map[string]int{`slow`: 1}},
+		},
+		{
+			name: "Fails when the pattern doesn't compile",
+			w:    Assertion{PatternCounts: map[string]int{`(`: 1}},
+			err:  "count pattern \"(\" did not compile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertPatternCounts(out, tt.w)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertPatternCounts() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertPatternCounts() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertNoControlChars(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		enabled bool
+		err     string
+	}{
+		{
+			name:    "Passes when the output only has tabs and newlines",
+			out:     "NAME\tSTATUS\ncluster\tstarted\n",
+			enabled: true,
+		},
+		{
+			name: "Ignores the control characters when disabled",
+			out:  "cluster\x00started\n",
+		},
+		{
+			name:    "Fails listing the offsets of the control characters",
+			out:     "cluster\x00started\x1b\n",
+			enabled: true,
+			err:     "found control characters at byte offsets 7 (U+0000), 15 (U+001B) in standard output, around the first: \"cluster\\x00started\\x1b\\n\"",
+		},
+		{
+			name:    "Doesn't report the whole output",
+			out:     strings.Repeat("a", 100) + "\x00" + strings.Repeat("b", 100),
+			enabled: true,
+			err:     "at byte offsets 100 (U+0000) in standard output, around the first: \"aaaaaaaaaaaaaaaa\\x00bbbbbbbbbbbbbbb\"",
+		},
+		{
+			name:    "Limits the number of reported offsets",
+			out:     strings.Repeat("\x07", 12),
+			enabled: true,
+			err:     "9 (U+0007), and 2 more in standard output",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertNoControlChars(tt.out, tt.enabled)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertNoControlChars() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertNoControlChars() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertValidUTF8(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		enabled bool
+		err     string
+	}{
+		{
+			name:    "Passes when the output is valid UTF-8",
+			out:     "región: açaí \uFFFD\n",
+			enabled: true,
+		},
+		{
+			name: "Ignores the invalid sequences when disabled",
+			out:  "regi\xf3n\n",
+		},
+		{
+			name:    "Reports the offset of the first invalid sequence",
+			out:     "región: regi\xf3n\n",
+			enabled: true,
+			err:     "found an invalid UTF-8 sequence at byte offset 13 in standard output: \"\\xf3n\\n\"",
+		},
+		{
+			name:    "Reports a truncated sequence at the end",
+			out:     "ok \xe2\x82",
+			enabled: true,
+			err:     "found an invalid UTF-8 sequence at byte offset 3 in standard output: \"\\xe2\\x82\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertValidUTF8(tt.out, tt.enabled)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertValidUTF8() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertValidUTF8() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertOrderedLines(t *testing.T) {
+	const out = "NAME    STATUS\ncluster started\nother   stopped\n"
+	tests := []struct {
+		name string
+		want []string
+		err  string
+	}{
+		{
+			name: "Passes when all the lines are equal",
+			want: []string{"NAME    STATUS", "cluster started", "other   stopped"},
+		},
+		{
+			name: "Reports the first line which differs",
+			want: []string{"NAME    STATUS", "cluster stopped", "other   started"},
+			err:  "line 1 got \"cluster started\" want \"cluster stopped\"",
+		},
+		{
+			name: "Fails when lines are missing",
+			want: []string{"NAME    STATUS", "cluster started", "other   stopped", "total 2"},
+			err:  "line 3 is missing, want \"total 2\"",
+		},
+		{
+			name: "Fails when there are more lines",
+			want: []string{"NAME    STATUS"},
+			err:  "line 1 \"cluster started\" is unexpected, want 1 lines",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertOrderedLines(out, tt.want)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertOrderedLines() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertOrderedLines() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertLineSet(t *testing.T) {
+	const out = "beta\nalpha\n\ngamma\nalpha\n"
+	tests := []struct {
+		name string
+		want []string
+		err  string
+	}{
+		{
+			name: "Passes regardless of the order and repetitions",
+			want: []string{"alpha", "gamma", "beta"},
+		},
+		{
+			name: "Reports the missing lines",
+			want: []string{"alpha", "beta", "gamma", "delta"},
+			err:  "missing 1 lines: [\"delta\"]",
+		},
+		{
+			name: "Reports the unexpected lines",
+			want: []string{"alpha"},
+			err:  "found 2 unexpected lines: [\"beta\" \"gamma\"]",
+		},
+		{
+			name: "Reports both differences",
+			want: []string{"alpha", "beta", "delta", "delta"},
+			err:  "missing 1 lines: [\"delta\"]\nfound 1 unexpected lines: [\"gamma\"]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertLineSet(out, tt.want)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertLineSet() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertLineSet() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_extractSection(t *testing.T) {
+	const out = "Connecting...\n---BEGIN---\nid: a1\n---END---\nid: b2\nDone\n"
+	tests := []struct {
+		name       string
+		start, end string
+		want       string
+		err        string
+	}{
+		{
+			name:  "Extracts the section between the delimiters",
+			start: `---BEGIN---\n`,
+			end:   `---END---`,
+			want:  "id: a1\n",
+		},
+		{
+			name:  "Extracts the section up to the end of the output",
+			start: `---END---\n`,
+			want:  "id: b2\nDone\n",
+		},
+		{
+			name: "Extracts the section from the start of the output",
+			end:  `---BEGIN---`,
+			want: "Connecting...\n",
+		},
+		{
+			name:  "Fails when the start isn't found",
+			start: `---START---`,
+			err:   "couldn't match section start \"---START---\"",
+		},
+		{
+			name:  "Fails when the end isn't found after the start",
+			start: `---END---`,
+			end:   `---BEGIN---`,
+			err:   "couldn't match section end \"---BEGIN---\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractSection(out, tt.start, tt.end)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("extractSection() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("extractSection() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_assertBinary(t *testing.T) {
+	const out = "\x89PNG\r\n\x1a\n\x00\x00"
+	length := func(n int) *int { return &n }
+	tests := []struct {
+		name string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "Finds the hex fragments ignoring whitespace and case",
+			w:    Assertion{HexContains: []string{"89 50 4E 47", "0d0a1a0a"}, OutputByteLen: length(10)},
+		},
+		{
+			name: "Fails when the hex fragment isn't found",
+			w:    Assertion{HexContains: []string{"ffd8ff"}},
+			err:  "didn't find hex \"ffd8ff\" in the 10 bytes of standard output starting with: 89504e470d0a1a0a0000",
+		},
+		{
+			name: "Fails when the byte length differs",
+			w:    Assertion{OutputByteLen: length(8)},
+			err:  "standard output has 10 bytes, want 8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertBinary(out, tt.w)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertBinary() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertBinary() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertBinary_truncatesHex(t *testing.T) {
+	out := strings.Repeat("\x00", 1000)
+	err := assertBinary(out, Assertion{HexContains: []string{"ff"}})
+	want := "in the 1000 bytes of standard output starting with: " + strings.Repeat("0", maxHexPrefix) + "..."
+	if err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("assertBinary() error = %v, want suffix %v", err, want)
+	}
+}
+
+func Test_assertMaxErrorMatches(t *testing.T) {
+	const stderr = "WARN flag --a is deprecated\nWARN flag --b is deprecated\nINFO done\n" +
+		"WARN flag --c is deprecated\nWARN flag --d is deprecated\n"
+	tests := []struct {
+		name   string
+		limits []ErrorMatchLimit
+		err    string
+	}{
+		{
+			name:   "Passes when the matches don't exceed the maximum",
+			limits: []ErrorMatchLimit{{Pattern: `deprecated`, Max: 4}, {Pattern: `^ERROR`}},
+		},
+		{
+			name:   "Fails reporting the count and a sample of the lines",
+			limits: []ErrorMatchLimit{{Pattern: `deprecated`, Max: 2}},
+			err: "pattern \"deprecated\" matched 4 lines in standard error, want at most 2, e.g. " +
+				"\"WARN flag --a is deprecated\", \"WARN flag --b is deprecated\", \"WARN flag --c is deprecated\"",
+		},
+		{
+			name:   "Fails when the pattern doesn't compile",
+			limits: []ErrorMatchLimit{{Pattern: `(`}},
+			err:    "error match pattern \"(\" did not compile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertMaxErrorMatches(stderr, tt.limits)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertMaxErrorMatches() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertMaxErrorMatches() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertTimeout(t *testing.T) {
+	tb := &TimeoutBehavior{Within: time.Second, ErrContains: "deadline exceeded"}
+	tests := []struct {
+		name     string
+		tb       *TimeoutBehavior
+		duration time.Duration
+		err      error
+		stderr   string
+		want     []string
+	}{
+		{
+			name: "no timeout behavior",
+		},
+		{
+			name:     "fails within the duration",
+			tb:       tb,
+			duration: 500 * time.Millisecond,
+			err:      errors.New("exit status 1"),
+			stderr:   "Error: context deadline exceeded\n",
+		},
+		{
+			name:     "returns after the duration",
+			tb:       tb,
+			duration: 2 * time.Second,
+			err:      errors.New("exit status 1"),
+			stderr:   "Error: context deadline exceeded\n",
+			want:     []string{"command took 2s, want it to return within 1s"},
+		},
+		{
+			name:     "succeeds without the error",
+			tb:       tb,
+			duration: 500 * time.Millisecond,
+			stderr:   "done\n",
+			want: []string{
+				"command succeeded, want it to fail",
+				"standard error doesn't contain \"deadline exceeded\": \"done\n\"",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertTimeout(tt.tb, tt.duration, tt.err, tt.stderr)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("assertTimeout() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("assertTimeout() error = nil, want %v", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("assertTimeout() error = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteTests_timeoutSucceeded(t *testing.T) {
+	tests := Tests{{
+		Name:   "should have timed out",
+		Binary: mockBin,
+		Args:   mockArgs(enginetest.MockCommand{Stdout: "done\n"}),
+		Assert: Assertions{Timeout: &TimeoutBehavior{Within: time.Minute, ErrContains: "deadline exceeded"}},
+	}}
+	if inSubprocess() {
+		ExecuteTests(t, tests, WithCooldown(0))
+		return
+	}
+
+	out := runInSubprocess(t)
+	if got := strings.Count(out, "command succeeded, want it to fail"); got != 1 {
+		t.Errorf("output = %s, want the success reported once", out)
+	}
+	if strings.Contains(out, "exited zero but expected an error") {
+		t.Errorf("output = %s, want the success only reported by the timeout assertion", out)
+	}
+}
+
+func Test_assertSorted(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "no sorted assertion",
+			out:  "b\na\n",
+		},
+		{
+			name: "ascending lines",
+			out:  "api/latest\napi/v0\napi/v0.1\napp\n",
+			w:    Assertion{SortedAscending: true},
+		},
+		{
+			name: "descending lines",
+			out:  "c\nb\nb\na\n",
+			w:    Assertion{SortedDescending: true},
+		},
+		{
+			name: "filters the header",
+			out:  "NAME\nalpha\nbeta\n",
+			w:    Assertion{SortedAscending: true, SortedLineFilter: "^[a-z]"},
+		},
+		{
+			name: "reports the first out of order pair",
+			out:  "a\nc\nb\nd\na\n",
+			w:    Assertion{SortedAscending: true},
+			err:  "must be sorted\nline \"b\" is not in ascending order after line \"c\"",
+		},
+		{
+			name: "reports descending order",
+			out:  "b\nc\n",
+			w:    Assertion{SortedDescending: true},
+			err:  "must be sorted\nline \"c\" is not in descending order after line \"b\"",
+		},
+		{
+			name: "invalid filter",
+			out:  "a\n",
+			w:    Assertion{SortedAscending: true, SortedLineFilter: "("},
+			err:  "must be sorted\nline filter \"(\" did not compile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertSorted(tt.out, tt.w)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("assertSorted() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Errorf("assertSorted() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_assertMust_stripLinePrefix(t *testing.T) {
+	const out = "1: cluster started\n2: 3 nodes\n10: cluster healthy\n"
+	tests := []struct {
+		name string
+		out  string
+		w    Assertion
+		err  string
+	}{
+		{
+			name: "Strips the line numbers",
+			out:  out,
+			w: Assertion{
+				StripLinePrefix: `\d+: `,
+				Strict:          true,
+				Output:          []string{"cluster started\n3 nodes\ncluster healthy\n"},
+			},
+		},
+		{
+			name: "Strips the prefix only at the start of the lines",
+			out:  "[INFO] started [INFO]\n[WARN] slow\n",
+			w: Assertion{
+				StripLinePrefix: `\[[A-Z]+\] `,
+				Lines:           []string{"started [INFO]", "slow"},
+			},
+		},
+		{
+			name: "Fails on an invalid pattern",
+			out:  out,
+			w:    Assertion{StripLinePrefix: "("},
+			err:  "must strip line prefix\nline prefix pattern \"(\" did not compile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertMust(tt.out, "", tt.w, teststorage.NewSafeMap(), time.Now)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertMust() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertMust() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_dedupeLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		out    string
+		global bool
+		want   string
+	}{
+		{
+			name: "Removes the adjacent duplicates",
+			out:  "retrying\nretrying\nconnected\nretrying\n",
+			want: "retrying\nconnected\nretrying\n",
+		},
+		{
+			name:   "Removes every duplicate",
+			out:    "retrying\nretrying\nconnected\nretrying\n",
+			global: true,
+			want:   "retrying\nconnected\n",
+		},
+		{
+			name: "Removes a duplicate without a line ending",
+			out:  "connected\nconnected",
+			want: "connected\n",
+		},
+		{
+			name: "Keeps the output without duplicates",
+			out:  "a\nb\n",
+			want: "a\nb\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dedupeLines(tt.out, tt.global); got != tt.want {
+				t.Errorf("dedupeLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
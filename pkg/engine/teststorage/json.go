@@ -0,0 +1,57 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package teststorage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrKeyNotFound is returned when the key isn't found in the storage.
+var ErrKeyNotFound = errors.New("key not found")
+
+// SetJSON encodes the value as JSON and persists it into the key, so
+// structured values can be stored in any Storage.
+func SetJSON(s Storage, k string, v interface{}) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed encoding the value of key \"%s\": %w", k, err)
+	}
+	s.Set(k, string(encoded))
+	return nil
+}
+
+// GetJSON decodes the JSON value of the key into out, which must be a pointer.
+// Returns ErrKeyNotFound when the key is not found.
+func GetJSON(s Storage, k string, out interface{}) error {
+	v, ok := s.Get(k)
+	if !ok {
+		return fmt.Errorf("%w: \"%s\"", ErrKeyNotFound, k)
+	}
+	if err := json.Unmarshal([]byte(v), out); err != nil {
+		return fmt.Errorf("failed decoding the value of key \"%s\": %w", k, err)
+	}
+	return nil
+}
+
+// SetJSON encodes the value as JSON and persists it into the key.
+func (m *SafeMap) SetJSON(k string, v interface{}) error { return SetJSON(m, k, v) }
+
+// GetJSON decodes the JSON value of the key into out, which must be a pointer.
+func (m *SafeMap) GetJSON(k string, out interface{}) error { return GetJSON(m, k, out) }
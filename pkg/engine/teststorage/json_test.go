@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package teststorage
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSafeMap_JSON(t *testing.T) {
+	type cluster struct {
+		ID    string   `json:"id"`
+		Nodes []string `json:"nodes"`
+	}
+
+	m := NewSafeMap()
+	want := cluster{ID: "a1", Nodes: []string{"n1", "n2"}}
+	if err := m.SetJSON("cluster", want); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.Get("cluster"); got != `{"id":"a1","nodes":["n1","n2"]}` {
+		t.Errorf("SafeMap.SetJSON() stored = %v", got)
+	}
+
+	var got cluster
+	if err := m.GetJSON("cluster", &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SafeMap.GetJSON() = %v, want %v", got, want)
+	}
+
+	if err := m.GetJSON("unexisting", &got); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("SafeMap.GetJSON() error = %v, want %v", err, ErrKeyNotFound)
+	}
+
+	m.Set("text", "not json")
+	err := m.GetJSON("text", &got)
+	if want := "failed decoding the value of key \"text\""; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("SafeMap.GetJSON() error = %v, want %v", err, want)
+	}
+
+	err = m.SetJSON("channel", make(chan int))
+	if want := "failed encoding the value of key \"channel\""; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("SafeMap.SetJSON() error = %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,50 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package teststorage
+
+import "strings"
+
+// GlobalKeyPrefix marks the keys which a namespaced storage reads and writes
+// without its namespace, so values can still be deliberately shared.
+const GlobalKeyPrefix = "global."
+
+// Namespaced returns a view of the storage which prefixes every key with the
+// namespace, except the keys starting with GlobalKeyPrefix, which are passed
+// through unchanged.
+func Namespaced(s Storage, namespace string) Storage {
+	return namespaced{storage: s, namespace: namespace}
+}
+
+type namespaced struct {
+	storage   Storage
+	namespace string
+}
+
+// key returns the key of the underlying storage.
+func (n namespaced) key(k string) string {
+	if strings.HasPrefix(k, GlobalKeyPrefix) {
+		return k
+	}
+	return n.namespace + "/" + k
+}
+
+// Set persists a string value into the namespaced key.
+func (n namespaced) Set(k, v string) { n.storage.Set(n.key(k), v) }
+
+// Get obtains the value of the namespaced key.
+func (n namespaced) Get(k string) (string, bool) { return n.storage.Get(n.key(k)) }
@@ -0,0 +1,58 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package teststorage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamespaced(t *testing.T) {
+	m := NewSafeMap()
+	a, b := Namespaced(m, "a"), Namespaced(m, "b")
+
+	a.Set("id", "1")
+	b.Set("id", "2")
+	a.Set("global.id", "3")
+
+	tests := []struct {
+		name    string
+		storage Storage
+		key     string
+		want    string
+		found   bool
+	}{
+		{name: "reads its own key", storage: a, key: "id", want: "1", found: true},
+		{name: "doesn't read other namespaces", storage: b, key: "id", want: "2", found: true},
+		{name: "reads the global keys", storage: b, key: "global.id", want: "3", found: true},
+		{name: "doesn't find a missing key", storage: a, key: "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := tt.storage.Get(tt.key)
+			if got != tt.want || found != tt.found {
+				t.Errorf("Get() = %v, %v, want %v, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+
+	want := map[string]string{"a/id": "1", "b/id": "2", "global.id": "3"}
+	if !reflect.DeepEqual(m.db, want) {
+		t.Errorf("db = %v, want %v", m.db, want)
+	}
+}
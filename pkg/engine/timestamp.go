@@ -0,0 +1,78 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// RecentTimestamp ensures that a timestamp printed by the command, such as a
+// "created at" time, is recent. The timestamp is compared to the current time
+// of the machine rather than the suite's Clock, since it's the command which
+// prints it.
+type RecentTimestamp struct {
+	// Regex pattern which captures the timestamp in its first group, or in
+	// the whole match when the pattern has no groups.
+	Pattern string
+
+	// Layout of the timestamp as accepted by time.Parse, such as
+	// time.RFC3339. Timestamps without a time zone are in local time.
+	Layout string
+
+	// Maximum age of the timestamp. Timestamps in the future aren't recent.
+	MaxAge time.Duration
+}
+
+func assertRecentTimestamps(out string, timestamps []RecentTimestamp, now time.Time) error {
+	var errs []error
+	for _, ts := range timestamps {
+		re, err := regexp.Compile(ts.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("timestamp pattern \"%s\" did not compile", ts.Pattern))
+			continue
+		}
+
+		match := re.FindStringSubmatch(out)
+		if match == nil {
+			errs = append(errs, fmt.Errorf("couldn't match timestamp pattern \"%s\" to standard output: \"%s\"", ts.Pattern, out))
+			continue
+		}
+
+		captured := firstGroup(match)
+		parsed, err := time.ParseInLocation(ts.Layout, captured, time.Local)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("timestamp \"%s\" doesn't match layout \"%s\": %w", captured, ts.Layout, err))
+			continue
+		}
+
+		oldest := now.Add(-ts.MaxAge)
+		if parsed.Before(oldest) || parsed.After(now) {
+			errs = append(errs, fmt.Errorf("timestamp %s is not within [%s, %s]",
+				parsed.Format(time.RFC3339Nano), oldest.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano),
+			))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewPrefixedError("must find recent timestamps", errors.Join(errs...))
+	}
+	return nil
+}
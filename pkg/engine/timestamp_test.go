@@ -0,0 +1,109 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/testcli/pkg/engine/teststorage"
+)
+
+func Test_assertRecentTimestamps(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	created := RecentTimestamp{Pattern: `created at (\S+)`, Layout: time.RFC3339, MaxAge: time.Minute}
+	tests := []struct {
+		name       string
+		out        string
+		timestamps []RecentTimestamp
+		err        string
+	}{
+		{
+			name:       "recent timestamp",
+			out:        "deployment created at 2024-03-01T11:59:30Z\n",
+			timestamps: []RecentTimestamp{created},
+		},
+		{
+			name: "recent timestamp without groups",
+			out:  "2024-03-01T11:59:59Z deployment created\n",
+			timestamps: []RecentTimestamp{{
+				Pattern: `\d{4}-\d{2}-\d{2}T\S+Z`,
+				Layout:  time.RFC3339,
+				MaxAge:  time.Hour,
+			}},
+		},
+		{
+			name:       "old timestamp",
+			out:        "deployment created at 2024-03-01T11:58:00Z\n",
+			timestamps: []RecentTimestamp{created},
+			err:        "timestamp 2024-03-01T11:58:00Z is not within [2024-03-01T11:59:00Z, 2024-03-01T12:00:00Z]",
+		},
+		{
+			name:       "future timestamp",
+			out:        "deployment created at 2024-03-01T12:00:01Z\n",
+			timestamps: []RecentTimestamp{created},
+			err:        "timestamp 2024-03-01T12:00:01Z is not within",
+		},
+		{
+			name:       "unparseable timestamp",
+			out:        "deployment created at yesterday\n",
+			timestamps: []RecentTimestamp{created},
+			err:        "timestamp \"yesterday\" doesn't match layout",
+		},
+		{
+			name:       "no timestamp",
+			out:        "deployment created\n",
+			timestamps: []RecentTimestamp{created},
+			err:        "couldn't match timestamp pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertRecentTimestamps(tt.out, tt.timestamps, now)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("assertRecentTimestamps() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("assertRecentTimestamps() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAssertions_ensureNow(t *testing.T) {
+	a := Assertions{Must: Assertion{RecentTimestamps: []RecentTimestamp{{
+		Pattern: `created at (\S+)`,
+		Layout:  time.RFC3339,
+		MaxAge:  time.Minute,
+	}}}}
+	stdout := "created at 2001-02-03T04:05:00Z\n"
+	now := func() time.Time { return time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC) }
+
+	if err := a.ensure(bytes.NewBufferString(stdout), &bytes.Buffer{}, nil, teststorage.NewSafeMap(), "", now); err != nil {
+		t.Errorf("Assertions.ensure() error = %v", err)
+	}
+	// Ensure compares the timestamp to the machine's time.
+	if err := a.Ensure(bytes.NewBufferString(stdout), &bytes.Buffer{}, nil, teststorage.NewSafeMap(), ""); err == nil {
+		t.Error("Assertions.Ensure() error = nil, want the timestamp to be too old")
+	}
+}
@@ -0,0 +1,88 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TempDirPlaceholder is replaced in the arguments, the WorkingDir, the
+// PreCommands, the Env values and the FileAssertions paths of a test with the
+// path of its TempDir.
+const TempDirPlaceholder = "{{.tmpdir}}"
+
+// makeTempDir creates the scratch directory of a test. It's removed once the
+// test finishes, unless keepOnFailure is set and the test failed, in which
+// case its path is logged so the files can be inspected.
+func makeTempDir(t *testing.T, keepOnFailure bool) (string, error) {
+	dir, err := os.MkdirTemp("", "testcli-")
+	if err != nil {
+		return "", fmt.Errorf("failed creating the temporary directory: %w", err)
+	}
+
+	t.Cleanup(func() {
+		if keepOnFailure && t.Failed() {
+			t.Logf("kept the temporary directory of the failed test: %s", dir)
+			return
+		}
+		os.RemoveAll(dir)
+	})
+	return dir, nil
+}
+
+// expandEnvPlaceholders returns a copy of the environment variables with the
+// placeholders in their values replaced.
+func expandEnvPlaceholders(env map[string]string, values map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	expanded := make(map[string]string, len(env))
+	for name, value := range env {
+		expanded[name] = expandPlaceholders([]string{value}, values)[0]
+	}
+	return expanded
+}
+
+// expandFilePlaceholders returns a copy of the file assertions with the
+// placeholders in their paths replaced.
+func expandFilePlaceholders(files []FileAssertion, values map[string]string) []FileAssertion {
+	if files == nil {
+		return nil
+	}
+	expanded := make([]FileAssertion, len(files))
+	for i, f := range files {
+		f.Path = expandPlaceholders([]string{f.Path}, values)[0]
+		expanded[i] = f
+	}
+	return expanded
+}
+
+// expandCommandPlaceholders returns a copy of the commands with the
+// placeholders in their binaries and arguments replaced.
+func expandCommandPlaceholders(commands [][]string, values map[string]string) [][]string {
+	if commands == nil {
+		return nil
+	}
+	expanded := make([][]string, len(commands))
+	for i, command := range commands {
+		expanded[i] = expandPlaceholders(command, values)
+	}
+	return expanded
+}
@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+)
+
+func Test_makeTempDir(t *testing.T) {
+	var dir string
+	t.Run("scratch", func(t *testing.T) {
+		var err error
+		if dir, err = makeTempDir(t, true); err != nil {
+			t.Fatal(err)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Errorf("makeTempDir() didn't create the directory: %v", err)
+		}
+	})
+
+	// The test passed, so the directory is removed regardless of
+	// keepOnFailure.
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("makeTempDir() didn't remove the directory: %v", err)
+	}
+}
+
+func Test_expandEnvPlaceholders(t *testing.T) {
+	env := map[string]string{"OUT_DIR": TempDirPlaceholder, "CONFIG": TempDirPlaceholder + "/config", "REGION": "us-east-1"}
+	got := expandEnvPlaceholders(env, map[string]string{TempDirPlaceholder: "/tmp/scratch"})
+	want := map[string]string{"OUT_DIR": "/tmp/scratch", "CONFIG": "/tmp/scratch/config", "REGION": "us-east-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandEnvPlaceholders() = %v, want %v", got, want)
+	}
+	if env["OUT_DIR"] != TempDirPlaceholder {
+		t.Error("expandEnvPlaceholders() modified the original environment")
+	}
+}
+
+func Test_expandFilePlaceholders(t *testing.T) {
+	files := []FileAssertion{
+		{Path: TempDirPlaceholder + "/report.txt", MustExist: true},
+		{Path: "local.txt"},
+	}
+	got := expandFilePlaceholders(files, map[string]string{TempDirPlaceholder: "/tmp/scratch"})
+	want := []FileAssertion{
+		{Path: "/tmp/scratch/report.txt", MustExist: true},
+		{Path: "local.txt"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandFilePlaceholders() = %v, want %v", got, want)
+	}
+	if files[0].Path != TempDirPlaceholder+"/report.txt" {
+		t.Error("expandFilePlaceholders() modified the original assertions")
+	}
+}
+
+func Test_expandCommandPlaceholders(t *testing.T) {
+	commands := [][]string{{"touch", TempDirPlaceholder + "/marker"}, {"true"}}
+	got := expandCommandPlaceholders(commands, map[string]string{TempDirPlaceholder: "/tmp/scratch"})
+	want := [][]string{{"touch", "/tmp/scratch/marker"}, {"true"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandCommandPlaceholders() = %v, want %v", got, want)
+	}
+	if commands[0][1] != TempDirPlaceholder+"/marker" {
+		t.Error("expandCommandPlaceholders() modified the original commands")
+	}
+}
+
+func TestExecuteTests_tempDirWorkingDir(t *testing.T) {
+	ExecuteTests(t, Tests{{
+		Name:        "runs in the temporary directory",
+		Binary:      mockBin,
+		Args:        mockArgs(enginetest.MockCommand{File: "marker"}),
+		TempDir:     true,
+		WorkingDir:  TempDirPlaceholder,
+		PreCommands: [][]string{{"touch", TempDirPlaceholder + "/marker"}},
+		FileAssertions: []FileAssertion{
+			{Path: "marker", MustExist: true},
+		},
+	}}, WithCooldown(0))
+}
@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import "fmt"
+
+// SizeRelation is how the size of an output relates to the size of another.
+type SizeRelation string
+
+const (
+	// Smaller outputs have strictly fewer bytes, such as with a --quiet flag.
+	Smaller SizeRelation = "smaller"
+	// Larger outputs have strictly more bytes, such as with a --verbose flag.
+	Larger SizeRelation = "larger"
+)
+
+// OutputSizeComparison runs the command again with other arguments once the
+// test passes, and compares the size of its output with the test's output,
+// such as to ensure that a --quiet flag suppresses the output.
+type OutputSizeComparison struct {
+	// Arguments of the second run, which replace all the test's arguments.
+	// The test's placeholders are replaced in them.
+	Args []string
+
+	// How the output of the second run must relate to the test's output.
+	Want SizeRelation
+
+	// Stream whose size is compared. Combined compares the size of both
+	// streams together. Defaults to Stdout.
+	Stream Stream
+}
+
+// outputSize returns the size of the compared stream.
+func (c OutputSizeComparison) outputSize(stdout, stderr int) int {
+	switch c.Stream {
+	case Stderr:
+		return stderr
+	case Combined:
+		return stdout + stderr
+	}
+	return stdout
+}
+
+// assertOutputSize ensures that the size of the second run relates to the
+// size of the first run as the comparison wants.
+func assertOutputSize(c OutputSizeComparison, first, second int) error {
+	var ok bool
+	switch c.Want {
+	case Smaller:
+		ok = second < first
+	case Larger:
+		ok = second > first
+	default:
+		return NewPrefixedError("must compare output size", fmt.Errorf(
+			"unknown size relation \"%s\", want \"%s\" or \"%s\"", c.Want, Smaller, Larger,
+		))
+	}
+	if ok {
+		return nil
+	}
+	return NewPrefixedError("must compare output size", fmt.Errorf(
+		"%s with arguments %q is %d bytes, want %s than the %d bytes of the test",
+		c.Stream, c.Args, second, c.Want, first,
+	))
+}
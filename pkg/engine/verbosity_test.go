@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_assertOutputSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		comparison    OutputSizeComparison
+		first, second int
+		err           string
+	}{
+		{
+			name:       "Passes when the output is smaller",
+			comparison: OutputSizeComparison{Args: []string{"--quiet"}, Want: Smaller},
+			first:      120,
+			second:     0,
+		},
+		{
+			name:       "Passes when the output is larger",
+			comparison: OutputSizeComparison{Args: []string{"--verbose"}, Want: Larger},
+			first:      120,
+			second:     480,
+		},
+		{
+			name:       "Fails when the output has the same size",
+			comparison: OutputSizeComparison{Args: []string{"--quiet"}, Want: Smaller},
+			first:      120,
+			second:     120,
+			err:        "standard output with arguments [\"--quiet\"] is 120 bytes, want smaller than the 120 bytes of the test",
+		},
+		{
+			name:       "Fails when the error is not larger",
+			comparison: OutputSizeComparison{Args: []string{"-v"}, Want: Larger, Stream: Stderr},
+			first:      64,
+			second:     32,
+			err:        "standard error with arguments [\"-v\"] is 32 bytes, want larger than the 64 bytes of the test",
+		},
+		{
+			name:       "Fails on an unknown relation",
+			comparison: OutputSizeComparison{Want: "equal"},
+			err:        "unknown size relation \"equal\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertOutputSize(tt.comparison, tt.first, tt.second)
+			if tt.err == "" && err != nil {
+				t.Errorf("assertOutputSize() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("assertOutputSize() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestOutputSizeComparison_outputSize(t *testing.T) {
+	tests := []struct {
+		stream Stream
+		want   int
+	}{
+		{stream: "", want: 10},
+		{stream: Stdout, want: 10},
+		{stream: Stderr, want: 4},
+		{stream: Combined, want: 14},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.stream), func(t *testing.T) {
+			if got := (OutputSizeComparison{Stream: tt.stream}).outputSize(10, 4); got != tt.want {
+				t.Errorf("outputSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// RequireVersion defines the version which the binary under test must report
+// before any of the tests are run.
+type RequireVersion struct {
+	// Binary which reports the version. Defaults to the binary of the first
+	// test, found as that test does when it has FindBinary set.
+	Binary string
+
+	// Arguments which make the binary print its version, e.g. "version".
+	Args []string
+
+	// Value which the version output must contain.
+	MustContain string
+}
+
+// WithRequireVersion ensures that the binary under test reports the required
+// version before running the suite, failing it right away otherwise. This
+// prevents a cascade of confusing failures when testing the wrong build.
+func WithRequireVersion(v RequireVersion) Option {
+	return func(c *config) { c.requireVersion = &v }
+}
+
+// checkVersion runs the binary with the version arguments, ensuring that its
+// output contains the required version.
+func checkVersion(ctx context.Context, v RequireVersion, tests Tests) error {
+	binary := v.Binary
+	if binary == "" && len(tests) > 0 {
+		binary = tests[0].Binary
+		if tests[0].FindBinary {
+			found, err := FindBinaryPath(tests[0].SearchRoot, binary)
+			if err != nil {
+				return fmt.Errorf("failed to find binary \"%s\" to check its version: %w", binary, err)
+			}
+			binary = filepath.Join(tests[0].SearchRoot, found)
+		}
+	}
+	if binary == "" {
+		return errors.New("binary not set, please set the binary to check the version of")
+	}
+
+	command := strings.Join(append([]string{binary}, v.Args...), " ")
+	stdout, stderr, err := runCommand(ctx, invocation{bin: binary, args: v.Args})
+	if err != nil {
+		return fmt.Errorf("failed to get the version with \"%s\": %w, stderr = %s", command, err, stderr)
+	}
+
+	// Some binaries print their version on the standard error.
+	if out := stdout.String() + stderr.String(); !strings.Contains(out, v.MustContain) {
+		return fmt.Errorf("version \"%s\" not found in the output of \"%s\": \"%s\"", v.MustContain, command, out)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/elastic/testcli/pkg/engine/enginetest"
+)
+
+func Test_checkVersion(t *testing.T) {
+	version := enginetest.MockCommand{Stdout: "cli version 1.2.3\n"}.Args()
+	failing := enginetest.MockCommand{ExitCode: 1}.Args()
+	tests := []struct {
+		name  string
+		v     RequireVersion
+		tests Tests
+		err   string
+	}{
+		{
+			name: "Passes when the output contains the version",
+			v:    RequireVersion{Binary: mockBin, Args: version, MustContain: "1.2.3"},
+		},
+		{
+			name:  "Defaults to the binary of the first test",
+			v:     RequireVersion{Args: version, MustContain: "1.2.3"},
+			tests: Tests{{Binary: mockBin}},
+		},
+		{
+			name: "Finds the version on the standard error",
+			v:    RequireVersion{Binary: mockBin, Args: enginetest.MockCommand{Stderr: "1.2.3\n"}.Args(), MustContain: "1.2.3"},
+		},
+		{
+			name: "Fails when the version doesn't match",
+			v:    RequireVersion{Binary: mockBin, Args: version, MustContain: "2.0.0"},
+			err:  "version \"2.0.0\" not found in the output of \"" + strings.Join(append([]string{mockBin}, version...), " ") + "\"",
+		},
+		{
+			name: "Fails when the command errors",
+			v:    RequireVersion{Binary: mockBin, Args: failing, MustContain: "1.2.3"},
+			err:  "failed to get the version with \"" + strings.Join(append([]string{mockBin}, failing...), " ") + "\": exit status 1",
+		},
+		{
+			name: "Fails when there's no binary",
+			err:  "binary not set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkVersion(context.Background(), tt.v, tt.tests)
+			if tt.err == "" && err != nil {
+				t.Errorf("checkVersion() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("checkVersion() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}